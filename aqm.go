@@ -0,0 +1,66 @@
+package queue
+
+import (
+	"time"
+
+	"github.com/koykov/queue/qos"
+)
+
+// AQM (active queue management) drops items before queue becomes full to avoid large standing queues and latency.
+//
+// Dropped items route to DLQ by leak path and register by QueueLeak metric, so AQM works only in leaky mode (see
+// Config.DLQ). Policy may drop incoming items (Admit) or items taken by workers (Keep).
+// See aqm/ package for builtin policies RED/WRED and CoDel.
+type AQM interface {
+	// Admit checks incoming item considering queue state snapshot. Returns false to drop the item (rear leak).
+	Admit(snap AQMSnapshot) bool
+	// Keep checks item taken by worker considering its sojourn time (how long item waited in the queue).
+	// Returns false to drop the item (front leak).
+	Keep(sojourn time.Duration, now time.Time) bool
+}
+
+// AQMKeeper describes AQM policy that drops only items taken by workers (Admit always returns true).
+// Queue doesn't call Admit and doesn't make snapshot of incoming items for such policies.
+type AQMKeeper interface {
+	AQM
+	// KeepOnly returns true if policy doesn't check incoming items.
+	KeepOnly() bool
+}
+
+// AQMSnapshot describes queue state at the enqueue moment.
+type AQMSnapshot struct {
+	// Queue fullness rate.
+	Rate float32
+	// Name of item's sub-queue (prioritized queues only).
+	Subq string
+	// Drop profile of item's sub-queue (prioritized queues only, may be nil).
+	Profile *qos.DropProfile
+}
+
+// Check if AQM policy doesn't need Admit call.
+func aqmKeepOnly(aqm AQM) bool {
+	k, ok := aqm.(AQMKeeper)
+	return ok && k.KeepOnly()
+}
+
+// Make AQM snapshot for incoming item.
+// Please note, snapshot classifies the item, so engine doesn't evaluate its priority again.
+func (q *Queue) aqmSnapshot(itm *item) AQMSnapshot {
+	snap := AQMSnapshot{Rate: q.Rate()}
+	if qc := q.c().QoS; qc != nil {
+		q.engine.classify(itm)
+		q1 := &qc.Queues[itm.subqi]
+		snap.Subq, snap.Profile = q1.Name, q1.DropProfile
+	}
+	return snap
+}
+
+// Check if item taken by worker must be dropped by AQM policy.
+func (q *Queue) aqmDrop(itm *item) bool {
+	aqm := q.c().AQM
+	if aqm == nil || !q.CheckBit(flagLeaky) || itm.enqueued == 0 {
+		return false
+	}
+	now := q.clk().Now()
+	return !aqm.Keep(now.Sub(time.Unix(0, itm.enqueued)), now)
+}
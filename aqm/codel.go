@@ -0,0 +1,112 @@
+package aqm
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/koykov/queue"
+)
+
+const (
+	defaultCoDelTarget   = time.Millisecond * 5
+	defaultCoDelInterval = time.Millisecond * 100
+)
+
+// CoDel (controlled delay) policy drops items taken by workers considering their sojourn time (see RFC 8289).
+//
+// When sojourn time exceeds Target during at least Interval, policy enters dropping state and drops items with
+// increasing frequency (Interval/sqrt(count)) until sojourn time falls below Target. Unlike RED, CoDel doesn't depend
+// on queue capacity, so it keeps latency low even for big queues.
+// Don't share it among many queues.
+type CoDel struct {
+	// Acceptable standing sojourn time.
+	// If this param omit defaultCoDelTarget (5ms) will use instead.
+	Target time.Duration
+	// Sliding window to detect standing queue. Should be greater than worst case item processing time.
+	// If this param omit defaultCoDelInterval (100ms) will use instead.
+	Interval time.Duration
+
+	once sync.Once
+	mux  sync.Mutex
+	// Time when sojourn time may be considered as standing.
+	firstAbove time.Time
+	// Dropping state flag and time of next drop.
+	dropping bool
+	dropNext time.Time
+	// Drops count in dropping state and its value in the previous state.
+	cnt, lastCnt uint32
+}
+
+// Admit always admits items, CoDel drops only items taken by workers.
+func (p *CoDel) Admit(_ queue.AQMSnapshot) bool {
+	return true
+}
+
+// KeepOnly reports that CoDel doesn't check incoming items, so queue may skip Admit call.
+func (p *CoDel) KeepOnly() bool {
+	return true
+}
+
+func (p *CoDel) Keep(sojourn time.Duration, now time.Time) bool {
+	p.once.Do(p.init)
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	okToDrop := false
+	if sojourn < p.Target {
+		p.firstAbove = time.Time{}
+	} else if p.firstAbove.IsZero() {
+		p.firstAbove = now.Add(p.Interval)
+	} else if !now.Before(p.firstAbove) {
+		okToDrop = true
+	}
+
+	if p.dropping {
+		if !okToDrop {
+			// Sojourn time fell below target, leave dropping state.
+			p.dropping = false
+			return true
+		}
+		if !now.Before(p.dropNext) {
+			p.cnt++
+			p.dropNext = p.control(p.dropNext)
+			return false
+		}
+		return true
+	}
+	if okToDrop {
+		p.dropping = true
+		// Resume drop frequency if dropping state left recently.
+		if delta := p.cnt - p.lastCnt; delta > 1 && now.Sub(p.dropNext) < 16*p.Interval {
+			p.cnt = delta
+		} else {
+			p.cnt = 1
+		}
+		p.lastCnt = p.cnt
+		p.dropNext = p.control(now)
+		return false
+	}
+	return true
+}
+
+// Dropping returns true if policy is in dropping state.
+func (p *CoDel) Dropping() bool {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	return p.dropping
+}
+
+func (p *CoDel) init() {
+	if p.Target <= 0 {
+		p.Target = defaultCoDelTarget
+	}
+	if p.Interval <= 0 {
+		p.Interval = defaultCoDelInterval
+	}
+}
+
+// Control law: time of next drop.
+func (p *CoDel) control(t time.Time) time.Time {
+	return t.Add(time.Duration(float64(p.Interval) / math.Sqrt(float64(p.cnt))))
+}
@@ -0,0 +1,36 @@
+package aqm
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCoDel(t *testing.T) {
+	p := &CoDel{Target: time.Millisecond * 5, Interval: time.Millisecond * 100}
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	stages := []struct {
+		name    string
+		offset  time.Duration
+		sojourn time.Duration
+		keep    bool
+	}{
+		{"below target", 0, time.Millisecond, true},
+		{"above target", time.Millisecond * 10, time.Millisecond * 10, true},
+		{"within interval", time.Millisecond * 50, time.Millisecond * 10, true},
+		{"first drop", time.Millisecond * 120, time.Millisecond * 10, false},
+		{"before next drop", time.Millisecond * 150, time.Millisecond * 10, true},
+		{"second drop", time.Millisecond * 220, time.Millisecond * 10, false},
+		{"next drop sooner", time.Millisecond * 295, time.Millisecond * 10, false},
+		{"recovered", time.Millisecond * 300, time.Millisecond, true},
+	}
+	for _, stage := range stages {
+		t.Run(stage.name, func(t *testing.T) {
+			if keep := p.Keep(stage.sojourn, t0.Add(stage.offset)); keep != stage.keep {
+				t.Errorf("keep mismatch: need %t, got %t", stage.keep, keep)
+			}
+		})
+	}
+	if p.Dropping() {
+		t.Error("policy must leave dropping state")
+	}
+}
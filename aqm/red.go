@@ -0,0 +1,128 @@
+package aqm
+
+import (
+	"sync"
+	"time"
+
+	"github.com/koykov/queue"
+	"github.com/koykov/queue/qos"
+	"github.com/koykov/queue/rng"
+)
+
+const (
+	defaultREDMinThreshold   = .5
+	defaultREDMaxThreshold   = .9
+	defaultREDMaxProbability = .1
+	defaultREDWeight         = .002
+)
+
+// RED (random early detection) policy drops incoming items with probability depending on average queue fullness
+// rate (see https://en.wikipedia.org/wiki/Random_early_detection).
+//
+// Average rate calculates as exponentially weighted moving average of queue rate on each enqueue. Prioritized queues
+// may specify own drop profile for each sub-queue (see qos.Queue.DropProfile), thus policy works as weighted RED
+// (WRED): average rate is common, but low priority sub-queues may start drop earlier and more aggressive.
+// Don't share it among many queues.
+type RED struct {
+	// Average rate to start early drop.
+	// If this param omit defaultREDMinThreshold (0.5) will use instead.
+	MinThreshold float32
+	// Average rate to drop all incoming items.
+	// If this param omit defaultREDMaxThreshold (0.9) will use instead.
+	MaxThreshold float32
+	// Drop probability at MaxThreshold.
+	// If this param omit defaultREDMaxProbability (0.1) will use instead.
+	MaxProbability float32
+	// Weight of actual rate in average rate in range (0..1]. Small values smooth bursts.
+	// If this param omit defaultREDWeight (0.002) will use instead.
+	Weight float32
+	// Random number generator.
+	// If this param omit rng.Pool will use instead.
+	RNG rng.Interface
+
+	once sync.Once
+	mux  sync.Mutex
+	avg  float64
+	// Items admitted since last drop by sub-queues.
+	cnt map[string]int
+}
+
+func (p *RED) Admit(snap queue.AQMSnapshot) bool {
+	p.once.Do(p.init)
+	mn, mx, mp := p.profile(snap.Profile)
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	w := float64(p.Weight)
+	p.avg = (1-w)*p.avg + w*float64(snap.Rate)
+	switch {
+	case p.avg < mn:
+		p.cnt[snap.Subq] = 0
+		return true
+	case p.avg >= mx:
+		p.cnt[snap.Subq] = 0
+		return false
+	}
+	// Linear drop probability, spread uniformly among admitted items.
+	pb := mp * (p.avg - mn) / (mx - mn)
+	c := p.cnt[snap.Subq]
+	pa := 1.0
+	if d := 1 - float64(c)*pb; d > 0 {
+		pa = pb / d
+	}
+	if p.RNG.Float64() < pa {
+		p.cnt[snap.Subq] = 0
+		return false
+	}
+	p.cnt[snap.Subq] = c + 1
+	return true
+}
+
+// Keep always keeps items, RED drops only incoming items.
+func (p *RED) Keep(_ time.Duration, _ time.Time) bool {
+	return true
+}
+
+// Average returns actual average rate.
+func (p *RED) Average() float64 {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	return p.avg
+}
+
+func (p *RED) init() {
+	if p.MinThreshold <= 0 {
+		p.MinThreshold = defaultREDMinThreshold
+	}
+	if p.MaxThreshold <= 0 {
+		p.MaxThreshold = defaultREDMaxThreshold
+	}
+	if p.MaxProbability <= 0 {
+		p.MaxProbability = defaultREDMaxProbability
+	}
+	if p.Weight <= 0 || p.Weight > 1 {
+		p.Weight = defaultREDWeight
+	}
+	if p.RNG == nil {
+		p.RNG = &rng.Pool{}
+	}
+	p.cnt = make(map[string]int)
+}
+
+// Get drop params considering sub-queue profile.
+func (p *RED) profile(dp *qos.DropProfile) (mn, mx, mp float64) {
+	mn, mx, mp = float64(p.MinThreshold), float64(p.MaxThreshold), float64(p.MaxProbability)
+	if dp == nil {
+		return
+	}
+	if dp.MinThreshold > 0 {
+		mn = float64(dp.MinThreshold)
+	}
+	if dp.MaxThreshold > 0 {
+		mx = float64(dp.MaxThreshold)
+	}
+	if dp.MaxProbability > 0 {
+		mp = float64(dp.MaxProbability)
+	}
+	return
+}
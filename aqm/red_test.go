@@ -0,0 +1,50 @@
+package aqm
+
+import (
+	"testing"
+
+	"github.com/koykov/queue"
+	"github.com/koykov/queue/qos"
+)
+
+func TestRED(t *testing.T) {
+	t.Run("thresholds", func(t *testing.T) {
+		p := &RED{Weight: 1}
+		if !p.Admit(queue.AQMSnapshot{Rate: .3}) {
+			t.Error("item below min threshold must be admitted")
+		}
+		if p.Admit(queue.AQMSnapshot{Rate: .95}) {
+			t.Error("item above max threshold must be dropped")
+		}
+	})
+	t.Run("average", func(t *testing.T) {
+		p := &RED{Weight: .5}
+		p.Admit(queue.AQMSnapshot{Rate: 1})
+		p.Admit(queue.AQMSnapshot{Rate: 1})
+		if avg := p.Average(); avg != .75 {
+			t.Errorf("average mismatch: need .75, got %f", avg)
+		}
+	})
+	t.Run("probability", func(t *testing.T) {
+		p := &RED{Weight: 1, MaxProbability: 1}
+		var drop int
+		for i := 0; i < 1000; i++ {
+			if !p.Admit(queue.AQMSnapshot{Rate: .7}) {
+				drop++
+			}
+		}
+		if drop < 300 || drop > 700 {
+			t.Errorf("drop rate outside expected range: %d/1000", drop)
+		}
+	})
+	t.Run("weighted", func(t *testing.T) {
+		p := &RED{Weight: 1}
+		low := &qos.DropProfile{MinThreshold: .2, MaxThreshold: .4}
+		if p.Admit(queue.AQMSnapshot{Rate: .5, Subq: "low", Profile: low}) {
+			t.Error("low priority item must be dropped")
+		}
+		if !p.Admit(queue.AQMSnapshot{Rate: .5, Subq: "high"}) {
+			t.Error("high priority item must be admitted")
+		}
+	})
+}
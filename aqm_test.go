@@ -0,0 +1,133 @@
+package queue
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/koykov/queue/qos"
+)
+
+// AQM stub with custom decision funcs.
+type funcAQM struct {
+	admit func(snap AQMSnapshot) bool
+	keep  func(sojourn time.Duration) bool
+}
+
+func (p funcAQM) Admit(snap AQMSnapshot) bool                  { return p.admit(snap) }
+func (p funcAQM) Keep(sojourn time.Duration, _ time.Time) bool { return p.keep(sojourn) }
+
+// AQM stub that checks only items taken by workers.
+type keepOnlyAQM struct {
+	funcAQM
+}
+
+func (keepOnlyAQM) KeepOnly() bool { return true }
+
+// Evaluator that counts Eval calls.
+type countEvaluator struct {
+	n uint32
+}
+
+func (e *countEvaluator) Eval(_ any) uint {
+	atomic.AddUint32(&e.n, 1)
+	return 50
+}
+
+func TestAQM(t *testing.T) {
+	run := func(t *testing.T, aqm AQM, w Worker) (envelopeDLQ, *StatsWriter) {
+		dlq, sw := envelopeDLQ{ch: make(chan *DeadLetter, 64)}, NewStatsWriter()
+		q, err := New(&Config{
+			Capacity:      10,
+			Workers:       1,
+			Worker:        w,
+			DLQ:           dlq,
+			AQM:           aqm,
+			MetricsWriter: sw,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 4; i++ {
+			_ = q.Enqueue(i)
+		}
+		_ = q.Close()
+		return dlq, sw
+	}
+	t.Run("admit", func(t *testing.T) {
+		var n int
+		aqm := funcAQM{
+			admit: func(_ AQMSnapshot) bool { n++; return n%2 == 1 },
+			keep:  func(_ time.Duration) bool { return true },
+		}
+		dlq, sw := run(t, aqm, &countWorker{})
+		for _, x := range []int{1, 3} {
+			dl := awaitDeadLetter(t, dlq)
+			if dl.Payload != x || dl.Reason != DLQReasonLeak || dl.Direction != LeakDirectionRear {
+				t.Errorf("dead letter mismatch: %+v", dl)
+			}
+		}
+		if s := sw.Stats(); s.Leak["rear"] != 2 {
+			t.Errorf("leak metrics mismatch: %v", s.Leak)
+		}
+	})
+	t.Run("classify", func(t *testing.T) {
+		var subq string
+		eval := &countEvaluator{}
+		dlq := envelopeDLQ{ch: make(chan *DeadLetter, 64)}
+		q, err := New(&Config{
+			Capacity: 10,
+			Workers:  1,
+			Worker:   &countWorker{},
+			DLQ:      dlq,
+			AQM: funcAQM{
+				admit: func(snap AQMSnapshot) bool { subq = snap.Subq; return true },
+				keep:  func(_ time.Duration) bool { return true },
+			},
+			QoS: qos.New(qos.PQ, eval).
+				AddQueue(qos.Queue{Name: "high", Capacity: 5, Weight: 1}).
+				AddQueue(qos.Queue{Name: "low", Capacity: 5, Weight: 1}),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 4; i++ {
+			_ = q.Enqueue(i)
+		}
+		_ = q.Close()
+		// Priority must be evaluated once per item despite of AQM snapshot.
+		if n := atomic.LoadUint32(&eval.n); n != 4 || subq == "" {
+			t.Errorf("classify mismatch: %d evaluations, sub-queue %q", n, subq)
+		}
+	})
+	t.Run("keep only", func(t *testing.T) {
+		var n int
+		aqm := keepOnlyAQM{funcAQM{
+			admit: func(_ AQMSnapshot) bool { n++; return false },
+			keep:  func(_ time.Duration) bool { return true },
+		}}
+		_, sw := run(t, aqm, &countWorker{})
+		if s := sw.Stats(); n != 0 || s.Leak["rear"] != 0 {
+			t.Errorf("admit must be skipped: %d calls, leak %v", n, s.Leak)
+		}
+	})
+	t.Run("keep", func(t *testing.T) {
+		w := &countWorker{}
+		aqm := funcAQM{
+			admit: func(_ AQMSnapshot) bool { return true },
+			keep:  func(_ time.Duration) bool { return false },
+		}
+		dlq, sw := run(t, aqm, w)
+		for i := 0; i < 4; i++ {
+			if dl := awaitDeadLetter(t, dlq); dl.Direction != LeakDirectionFront {
+				t.Errorf("dead letter mismatch: %+v", dl)
+			}
+		}
+		for i := 0; i < 1000 && sw.Stats().Leak["front"] < 4; i++ {
+			time.Sleep(time.Millisecond)
+		}
+		if s, n := sw.Stats(), atomic.LoadUint32(&w.n); s.Leak["front"] != 4 || s.Out != 0 || n != 0 {
+			t.Errorf("metrics mismatch: leak %v, out %d, processed %d", s.Leak, s.Out, n)
+		}
+	})
+}
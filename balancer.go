@@ -0,0 +1,67 @@
+package queue
+
+import "time"
+
+// Balancer calculates target number of active workers of balanced queue.
+//
+// Queue calls balancer on each calibration (see Config.HeartbeatInterval and Config.ForceCalibrationLimit) and applies
+// the difference between target and actual number of active workers: wakes up idle/sleeping workers or puts redundant
+// workers to sleep. Thus, balancer contains only decision logic and may implement any policy, eg: PID controller or
+// predictive model.
+type Balancer interface {
+	// Balance returns target number of active workers considering queue state snapshot.
+	// Queue clamps the result to range [WorkersMin...WorkersMax] of actual params.
+	Balance(snap BalanceSnapshot) uint32
+}
+
+// BalanceSnapshot describes queue state at the calibration moment.
+type BalanceSnapshot struct {
+	// Queue fullness rate.
+	Rate float32
+	// Number of active workers.
+	WorkersUp uint32
+	// Realtime queue params considering schedule rules.
+	Params ScheduleParams
+	// Limit of workers could send to sleep at once (see Config.SleepThreshold).
+	SleepThreshold uint32
+	// Number of processed items since previous calibration.
+	Processed uint64
+	// Average item execution time since previous calibration.
+	ExecLatency time.Duration
+	// Failed to processed items ratio since previous calibration.
+	ErrorRate float32
+	// Time passed since previous calibration.
+	Interval time.Duration
+	// Number of processed items per second since previous calibration.
+	Throughput float64
+	// Number of enqueued items per second since previous calibration.
+	EnqueueRate float64
+}
+
+// FullnessBalancer is a default balancer that considers only queue fullness rate.
+//
+// When rate exceeds WakeupFactor one worker wakes up. When rate fell less than SleepFactor workers put to sleep by
+// chunks of WorkersUp/2 (considering SleepThreshold).
+type FullnessBalancer struct{}
+
+func (FullnessBalancer) Balance(snap BalanceSnapshot) uint32 {
+	up := snap.WorkersUp
+	switch {
+	case snap.Rate >= snap.Params.WakeupFactor:
+		// By default, only one worker starts at once. That's why need to keep heartbeat param enough small (<=1s).
+		return up + 1
+	case snap.Rate <= snap.Params.SleepFactor:
+		var chunk uint32
+		if chunk = up / 2; chunk == 0 {
+			chunk = 1
+		}
+		if st := snap.SleepThreshold; st > 0 && st < chunk {
+			chunk = st
+		}
+		if chunk > up {
+			return 0
+		}
+		return up - chunk
+	}
+	return up
+}
@@ -0,0 +1,68 @@
+package balancer
+
+import (
+	"time"
+
+	"github.com/koykov/queue"
+)
+
+const (
+	defaultAIMDIncrease       = 1
+	defaultAIMDDecreaseFactor = .5
+)
+
+// AIMD (additive increase/multiplicative decrease) balancer.
+//
+// While queue has backlog (rate exceeds WakeupFactor) workers count grows by Increase. When execution latency exceeds
+// LatencyThreshold or error rate exceeds ErrorThreshold, i.e. downstream saturates, workers count reduces by
+// DecreaseFactor. When queue is almost empty (rate fell less than SleepFactor) one worker puts to sleep.
+type AIMD struct {
+	// Maximum reasonable average execution time.
+	// Zero value disables the check.
+	LatencyThreshold time.Duration
+	// Maximum reasonable failed to processed items ratio.
+	// Zero value disables the check.
+	ErrorThreshold float32
+	// Additive increase step.
+	// If this param omit defaultAIMDIncrease (1) will use instead.
+	Increase uint32
+	// Multiplicative decrease factor in range (0..1).
+	// If this param omit defaultAIMDDecreaseFactor (0.5) will use instead.
+	DecreaseFactor float32
+}
+
+func (b AIMD) Balance(snap queue.BalanceSnapshot) uint32 {
+	up := snap.WorkersUp
+	if b.saturated(&snap) {
+		df := b.DecreaseFactor
+		if df <= 0 || df >= 1 {
+			df = defaultAIMDDecreaseFactor
+		}
+		return uint32(float32(up) * df)
+	}
+	switch {
+	case snap.Rate >= snap.Params.WakeupFactor:
+		inc := b.Increase
+		if inc == 0 {
+			inc = defaultAIMDIncrease
+		}
+		return up + inc
+	case snap.Rate <= snap.Params.SleepFactor && up > 0:
+		return up - 1
+	}
+	return up
+}
+
+// Check if downstream is saturated.
+func (b AIMD) saturated(snap *queue.BalanceSnapshot) bool {
+	if snap.Processed == 0 {
+		return false
+	}
+	if b.LatencyThreshold > 0 && snap.ExecLatency > b.LatencyThreshold {
+		return true
+	}
+	if b.ErrorThreshold > 0 && snap.ErrorRate > b.ErrorThreshold {
+		return true
+	}
+	return false
+}
@@ -0,0 +1,32 @@
+package balancer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/koykov/queue"
+)
+
+func TestAIMD(t *testing.T) {
+	params := queue.ScheduleParams{WorkersMin: 1, WorkersMax: 16, WakeupFactor: .75, SleepFactor: .25}
+	stages := []struct {
+		name   string
+		snap   queue.BalanceSnapshot
+		expect uint32
+	}{
+		{"backlog", queue.BalanceSnapshot{Rate: .8, WorkersUp: 4, Processed: 10, ExecLatency: time.Millisecond}, 6},
+		{"hold", queue.BalanceSnapshot{Rate: .5, WorkersUp: 4, Processed: 10, ExecLatency: time.Millisecond}, 4},
+		{"empty", queue.BalanceSnapshot{Rate: .1, WorkersUp: 4}, 3},
+		{"latency", queue.BalanceSnapshot{Rate: .9, WorkersUp: 8, Processed: 10, ExecLatency: time.Second}, 4},
+		{"errors", queue.BalanceSnapshot{Rate: .9, WorkersUp: 8, Processed: 10, ErrorRate: .3}, 4},
+	}
+	b := AIMD{LatencyThreshold: time.Millisecond * 100, ErrorThreshold: .1, Increase: 2}
+	for _, stage := range stages {
+		t.Run(stage.name, func(t *testing.T) {
+			stage.snap.Params = params
+			if target := b.Balance(stage.snap); target != stage.expect {
+				t.Errorf("target mismatch: need %d, got %d", stage.expect, target)
+			}
+		})
+	}
+}
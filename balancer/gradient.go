@@ -0,0 +1,99 @@
+package balancer
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/koykov/queue"
+)
+
+const (
+	defaultGradientTolerance     = 2
+	defaultGradientSmoothing     = .2
+	defaultGradientProbeInterval = 600
+	defaultGradientMinGradient   = .5
+)
+
+// Gradient balancer is an adaptation of gradient concurrency limit algorithm (see
+// https://github.com/Netflix/concurrency-limits).
+//
+// Balancer tracks minimal (no load) execution time and compares it with actual average execution time. Their ratio
+// (gradient) indicates downstream saturation and limits workers count by formula:
+// `limit = limit*gradient + sqrt(limit)`
+// where sqrt(limit) is a headroom to detect load growth. New limit applies with Smoothing factor. Workers count doesn't
+// grow while queue has no backlog (rate fell less than SleepFactor).
+// Don't share it among many queues.
+type Gradient struct {
+	// Tolerance of execution time growth relative to minimal execution time.
+	// If this param omit defaultGradientTolerance (2) will use instead.
+	Tolerance float64
+	// Smoothing factor of limit changes in range (0..1].
+	// If this param omit defaultGradientSmoothing (0.2) will use instead.
+	Smoothing float64
+	// Number of calibrations after which minimal execution time resets to probe new no load value.
+	// If this param omit defaultGradientProbeInterval (600) will use instead.
+	ProbeInterval uint32
+	// Maximum reasonable failed to processed items ratio. On exceed the limit cuts by half.
+	// Zero value disables the check.
+	ErrorThreshold float32
+
+	mux   sync.Mutex
+	limit float64
+	minEL time.Duration
+	probe uint32
+}
+
+func (b *Gradient) Balance(snap queue.BalanceSnapshot) uint32 {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	up := snap.WorkersUp
+	if b.limit == 0 {
+		b.limit = math.Max(float64(up), 1)
+	}
+	if snap.Processed == 0 || snap.ExecLatency == 0 {
+		// Nothing to measure.
+		if snap.Rate <= snap.Params.SleepFactor && up > 0 {
+			up--
+		}
+		b.limit = math.Max(float64(up), 1)
+		return up
+	}
+
+	tol, sm, pi := b.Tolerance, b.Smoothing, b.ProbeInterval
+	if tol <= 0 {
+		tol = defaultGradientTolerance
+	}
+	if sm <= 0 || sm > 1 {
+		sm = defaultGradientSmoothing
+	}
+	if pi == 0 {
+		pi = defaultGradientProbeInterval
+	}
+
+	// Track minimal execution time.
+	if b.probe++; b.probe >= pi {
+		b.probe, b.minEL = 0, 0
+	}
+	if b.minEL == 0 || snap.ExecLatency < b.minEL {
+		b.minEL = snap.ExecLatency
+	}
+
+	gradient := math.Min(1, math.Max(defaultGradientMinGradient, tol*float64(b.minEL)/float64(snap.ExecLatency)))
+	if b.ErrorThreshold > 0 && snap.ErrorRate > b.ErrorThreshold {
+		gradient = defaultGradientMinGradient
+	}
+	limit := b.limit*gradient + math.Sqrt(b.limit)
+	if snap.Rate <= snap.Params.SleepFactor && limit > b.limit {
+		// Queue has no backlog, so growth is senseless.
+		limit = b.limit
+	}
+	limit = b.limit*(1-sm) + limit*sm
+
+	// Keep limit in bounds to avoid windup.
+	limit = math.Max(limit, math.Max(float64(snap.Params.WorkersMin), 1))
+	limit = math.Min(limit, float64(snap.Params.WorkersMax))
+	b.limit = limit
+	return uint32(math.Round(limit))
+}
@@ -0,0 +1,47 @@
+package balancer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/koykov/queue"
+)
+
+func TestGradient(t *testing.T) {
+	params := queue.ScheduleParams{WorkersMin: 1, WorkersMax: 32, WakeupFactor: .75, SleepFactor: .25}
+	snap := func(up uint32, rate float32, el time.Duration) queue.BalanceSnapshot {
+		return queue.BalanceSnapshot{Rate: rate, WorkersUp: up, Params: params, Processed: 100, ExecLatency: el}
+	}
+	t.Run("grow", func(t *testing.T) {
+		b := Gradient{}
+		up := uint32(4)
+		for i := 0; i < 50; i++ {
+			up = b.Balance(snap(up, .9, time.Millisecond))
+		}
+		if up != params.WorkersMax {
+			t.Errorf("target mismatch: need %d, got %d", params.WorkersMax, up)
+		}
+	})
+	t.Run("saturation", func(t *testing.T) {
+		b := Gradient{}
+		up := uint32(16)
+		up = b.Balance(snap(up, .9, time.Millisecond))
+		for i := 0; i < 50; i++ {
+			// Execution time grows in proportion of workers count.
+			up = b.Balance(snap(up, .9, time.Millisecond*time.Duration(up)/4))
+		}
+		if up >= 16 {
+			t.Errorf("saturated downstream doesn't reduce workers: got %d", up)
+		}
+	})
+	t.Run("no backlog", func(t *testing.T) {
+		b := Gradient{}
+		up := uint32(4)
+		for i := 0; i < 10; i++ {
+			up = b.Balance(snap(up, .1, time.Millisecond))
+		}
+		if up > 4 {
+			t.Errorf("workers grow without backlog: got %d", up)
+		}
+	})
+}
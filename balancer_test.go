@@ -0,0 +1,62 @@
+package queue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFullnessBalancer(t *testing.T) {
+	params := ScheduleParams{WorkersMin: 2, WorkersMax: 16, WakeupFactor: .75, SleepFactor: .5}
+	stages := []struct {
+		name   string
+		rate   float32
+		up, st uint32
+		expect uint32
+	}{
+		{"wakeup", .8, 4, 0, 5},
+		{"hold", .6, 4, 0, 4},
+		{"sleep", .2, 10, 0, 5},
+		{"sleep threshold", .2, 10, 2, 8},
+		{"sleep last", .2, 1, 0, 0},
+	}
+	b := FullnessBalancer{}
+	for _, stage := range stages {
+		t.Run(stage.name, func(t *testing.T) {
+			snap := BalanceSnapshot{Rate: stage.rate, WorkersUp: stage.up, Params: params, SleepThreshold: stage.st}
+			if target := b.Balance(snap); target != stage.expect {
+				t.Errorf("target mismatch: need %d, got %d", stage.expect, target)
+			}
+		})
+	}
+}
+
+type fixedBalancer uint32
+
+func (b fixedBalancer) Balance(_ BalanceSnapshot) uint32 { return uint32(b) }
+
+type nopWorker struct{}
+
+func (nopWorker) Do(_ any) error { return nil }
+
+func TestBalancer(t *testing.T) {
+	t.Run("apply target", func(t *testing.T) {
+		q, err := New(&Config{
+			Capacity:          10,
+			WorkersMin:        1,
+			WorkersMax:        8,
+			HeartbeatInterval: time.Millisecond,
+			Worker:            nopWorker{},
+			Balancer:          fixedBalancer(5),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = q.ForceClose() }()
+		for i := 0; i < 1000 && q.getWorkersUp() != 5; i++ {
+			time.Sleep(time.Millisecond)
+		}
+		if up := q.getWorkersUp(); up != 5 {
+			t.Errorf("workers mismatch: need 5, got %d", up)
+		}
+	})
+}
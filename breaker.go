@@ -0,0 +1,227 @@
+package queue
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BreakerState represents circuit breaker state.
+type BreakerState uint32
+
+const (
+	// BreakerStateClosed is a regular state. All calls pass through the breaker.
+	BreakerStateClosed BreakerState = iota
+	// BreakerStateOpen denies all calls till OpenInterval passed.
+	BreakerStateOpen
+	// BreakerStateHalfOpen allows limited number of trial calls to check if downstream recovered.
+	BreakerStateHalfOpen
+)
+
+const (
+	// Default interval to keep breaker open.
+	defaultBreakerOpenInterval = time.Second * 5
+	// Default interval to reset failure counters in closed state.
+	defaultBreakerWindow = time.Second * 10
+	// Default number of trial calls in half-open state.
+	defaultBreakerHalfOpenRequests = 1
+	// How often paused workers check half-open breaker.
+	breakerPollInterval = time.Millisecond * 100
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerStateClosed:
+		return "closed"
+	case BreakerStateOpen:
+		return "open"
+	case BreakerStateHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// BreakerConfig describes circuit breaker properties.
+type BreakerConfig struct {
+	// FailureThreshold is a number of consecutive failures to open the breaker.
+	// Zero value disables the check.
+	FailureThreshold uint32
+	// FailureRate is a failures to calls ratio in range (0..1] to open the breaker.
+	// Zero value disables the check.
+	FailureRate float32
+	// MinRequests is a minimum number of calls in Window to consider FailureRate.
+	MinRequests uint32
+	// Window is an interval to reset calls/failures counters in closed state.
+	// If this param omit defaultBreakerWindow (10 seconds) will use instead.
+	Window time.Duration
+	// OpenInterval indicates how long breaker will stay open before switch to half-open state.
+	// If this param omit defaultBreakerOpenInterval (5 seconds) will use instead.
+	OpenInterval time.Duration
+	// HalfOpenRequests is a number of trial calls in half-open state. If all trial calls succeed, the breaker closes.
+	// Any failed trial call opens breaker again.
+	// If this param omit defaultBreakerHalfOpenRequests (1) will use instead.
+	HalfOpenRequests uint32
+
+	// Clock represents clock keeper.
+	// Queue overwrites this param with its own Config.Clock.
+	// If this param omit nativeClock will use instead (see clock.go).
+	Clock Clock
+}
+
+// Breaker is a circuit breaker implementation.
+//
+// Breaker may protect any Worker (see worker/breaker.go) or may be built in the queue using Config.Breaker param. In
+// the last case workers pause consumption while breaker is open and queue moves to StatusThrottle.
+// Don't share it among many queues.
+type Breaker struct {
+	conf BreakerConfig
+	mux  sync.Mutex
+	// Actual state.
+	state BreakerState
+	// Timestamp of last state switch or closed window start.
+	since int64
+	// Closed state counters.
+	calls, fails, consec uint32
+	// Half-open state counters.
+	trials, succ uint32
+	// State change callback.
+	notify func(from, to BreakerState)
+	// State change to notify after unlock.
+	from, to BreakerState
+	changed  bool
+}
+
+// NewBreaker makes new breaker instance and initialize it according config params.
+func NewBreaker(conf BreakerConfig) *Breaker {
+	return newBreaker(conf, nil)
+}
+
+func newBreaker(conf BreakerConfig, notify func(from, to BreakerState)) *Breaker {
+	if conf.Clock == nil {
+		conf.Clock = nativeClock{}
+	}
+	if conf.Window == 0 {
+		conf.Window = defaultBreakerWindow
+	}
+	if conf.OpenInterval == 0 {
+		conf.OpenInterval = defaultBreakerOpenInterval
+	}
+	if conf.HalfOpenRequests == 0 {
+		conf.HalfOpenRequests = defaultBreakerHalfOpenRequests
+	}
+	b := &Breaker{
+		conf:   conf,
+		since:  conf.Clock.Now().UnixNano(),
+		notify: notify,
+	}
+	return b
+}
+
+// Allow checks if call is possible.
+// Each allowed call must be finished by Report call.
+func (b *Breaker) Allow() bool {
+	b.mux.Lock()
+	defer b.unlock()
+	now := b.conf.Clock.Now().UnixNano()
+	switch b.state {
+	case BreakerStateOpen:
+		if time.Duration(now-b.since) < b.conf.OpenInterval {
+			return false
+		}
+		b.switchState(BreakerStateHalfOpen, now)
+		fallthrough
+	case BreakerStateHalfOpen:
+		if b.trials >= b.conf.HalfOpenRequests {
+			return false
+		}
+		b.trials++
+	default:
+		if time.Duration(now-b.since) >= b.conf.Window {
+			// Window is over, reset counters.
+			b.calls, b.fails, b.since = 0, 0, now
+		}
+	}
+	return true
+}
+
+// Report registers result of allowed call.
+func (b *Breaker) Report(err error) {
+	b.mux.Lock()
+	defer b.unlock()
+	now := b.conf.Clock.Now().UnixNano()
+	switch b.state {
+	case BreakerStateHalfOpen:
+		if err != nil {
+			b.switchState(BreakerStateOpen, now)
+			return
+		}
+		if b.succ++; b.succ >= b.conf.HalfOpenRequests {
+			b.switchState(BreakerStateClosed, now)
+		}
+	case BreakerStateClosed:
+		b.calls++
+		if err == nil {
+			b.consec = 0
+			return
+		}
+		b.fails++
+		b.consec++
+		if th := b.conf.FailureThreshold; th > 0 && b.consec >= th {
+			b.switchState(BreakerStateOpen, now)
+			return
+		}
+		if fr := b.conf.FailureRate; fr > 0 && b.calls >= b.conf.MinRequests &&
+			float32(b.fails)/float32(b.calls) >= fr {
+			b.switchState(BreakerStateOpen, now)
+		}
+	}
+}
+
+// State returns actual state of the breaker.
+func (b *Breaker) State() BreakerState {
+	return BreakerState(atomic.LoadUint32((*uint32)(&b.state)))
+}
+
+// Release allowed call that wasn't performed (eg: item skipped due to deadline).
+func (b *Breaker) cancel() {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	if b.state == BreakerStateHalfOpen && b.trials > 0 {
+		b.trials--
+	}
+}
+
+// Get interval to wait before next Allow attempt.
+func (b *Breaker) retryAfter() time.Duration {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	if b.state == BreakerStateOpen {
+		if d := b.conf.OpenInterval - time.Duration(b.conf.Clock.Now().UnixNano()-b.since); d > 0 {
+			return d
+		}
+		return 0
+	}
+	return breakerPollInterval
+}
+
+// Switch state and reset all counters.
+// Caution! Must be called under mutex. Callback calls after unlock (see unlock).
+func (b *Breaker) switchState(state BreakerState, now int64) {
+	if !b.changed {
+		b.from, b.changed = b.state, true
+	}
+	b.to = state
+	atomic.StoreUint32((*uint32)(&b.state), uint32(state))
+	b.since = now
+	b.calls, b.fails, b.consec, b.trials, b.succ = 0, 0, 0, 0, 0
+}
+
+// Unlock mutex and notify about state change made under it.
+func (b *Breaker) unlock() {
+	from, to, changed := b.from, b.to, b.changed
+	b.changed = false
+	b.mux.Unlock()
+	if changed && b.notify != nil {
+		b.notify(from, to)
+	}
+}
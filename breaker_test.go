@@ -0,0 +1,134 @@
+package queue
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Manual clock for testing purposes.
+type testClock struct {
+	mux sync.Mutex
+	now time.Time
+}
+
+func newTestClock(now time.Time) *testClock {
+	return &testClock{now: now}
+}
+
+func (c *testClock) Now() time.Time {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.now
+}
+
+func (c *testClock) Add(d time.Duration) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestBreaker(t *testing.T) {
+	errFail := errors.New("fail")
+	call := func(b *Breaker, err error) bool {
+		if !b.Allow() {
+			return false
+		}
+		b.Report(err)
+		return true
+	}
+	t.Run("consecutive", func(t *testing.T) {
+		clk := newTestClock(time.Now())
+		b := NewBreaker(BreakerConfig{FailureThreshold: 3, OpenInterval: time.Second, Clock: clk})
+		call(b, errFail)
+		call(b, errFail)
+		call(b, nil)
+		call(b, errFail)
+		call(b, errFail)
+		if b.State() != BreakerStateClosed {
+			t.Fatalf("state mismatch: need %s, got %s", BreakerStateClosed, b.State())
+		}
+		call(b, errFail)
+		if b.State() != BreakerStateOpen {
+			t.Fatalf("state mismatch: need %s, got %s", BreakerStateOpen, b.State())
+		}
+		if b.Allow() {
+			t.Error("open breaker allows call")
+		}
+	})
+	t.Run("rate", func(t *testing.T) {
+		clk := newTestClock(time.Now())
+		b := NewBreaker(BreakerConfig{FailureRate: .5, MinRequests: 10, Window: time.Minute, Clock: clk})
+		for i := 0; i < 9; i++ {
+			call(b, errFail)
+		}
+		if b.State() != BreakerStateClosed {
+			t.Fatalf("state mismatch: need %s, got %s", BreakerStateClosed, b.State())
+		}
+		// Window is over, counters must reset.
+		clk.Add(time.Minute)
+		for i := 0; i < 5; i++ {
+			call(b, nil)
+		}
+		for i := 0; i < 4; i++ {
+			call(b, errFail)
+		}
+		if b.State() != BreakerStateClosed {
+			t.Fatalf("state mismatch: need %s, got %s", BreakerStateClosed, b.State())
+		}
+		call(b, errFail)
+		if b.State() != BreakerStateOpen {
+			t.Fatalf("state mismatch: need %s, got %s", BreakerStateOpen, b.State())
+		}
+	})
+	t.Run("half-open", func(t *testing.T) {
+		clk := newTestClock(time.Now())
+		var trace []BreakerState
+		b := newBreaker(BreakerConfig{FailureThreshold: 1, OpenInterval: time.Second, HalfOpenRequests: 2, Clock: clk},
+			func(_, to BreakerState) { trace = append(trace, to) })
+		call(b, errFail)
+		clk.Add(time.Second)
+		// Trial call fails.
+		call(b, errFail)
+		clk.Add(time.Second)
+		if !b.Allow() || !b.Allow() {
+			t.Fatal("half-open breaker denies trial calls")
+		}
+		if b.Allow() {
+			t.Fatal("half-open breaker allows too many calls")
+		}
+		b.Report(nil)
+		b.Report(nil)
+		exp := []BreakerState{BreakerStateOpen, BreakerStateHalfOpen, BreakerStateOpen, BreakerStateHalfOpen,
+			BreakerStateClosed}
+		if len(trace) != len(exp) {
+			t.Fatalf("trace mismatch: need %v, got %v", exp, trace)
+		}
+		for i := range exp {
+			if trace[i] != exp[i] {
+				t.Fatalf("trace mismatch: need %v, got %v", exp, trace)
+			}
+		}
+	})
+	t.Run("notify", func(t *testing.T) {
+		var b *Breaker
+		var open bool
+		// Callback may use the breaker, so it must be called outside the mutex.
+		b = newBreaker(BreakerConfig{FailureThreshold: 1, OpenInterval: time.Second},
+			func(_, to BreakerState) { open = to == BreakerStateOpen && !b.Allow() })
+		done := make(chan struct{})
+		go func() {
+			call(b, errFail)
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("callback called under mutex")
+		}
+		if !open {
+			t.Error("callback must see open breaker")
+		}
+	})
+}
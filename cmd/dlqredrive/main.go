@@ -0,0 +1,148 @@
+// Command dlqredrive inspects and redrives file-backed DLQs.
+//
+// Usage:
+//
+//	dlqredrive [flags] file|dir...
+//
+// The command reads dead letters from given files or directories (line-delimited JSON files written by dlq.File) and
+// forwards payloads of matching items to HTTP endpoint (-url flag, each payload posts as JSON body) or prints them to
+// stdout as JSON lines.
+// Files stay untouched. Use -dry-run flag to count matching items only.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/koykov/queue"
+	"github.com/koykov/queue/dlq"
+)
+
+var (
+	fReasons  = flag.String("reason", "", "comma-separated reasons filter (leak, deadline, fail, close)")
+	fDryRun   = flag.Bool("dry-run", false, "count matching items only")
+	fLimit    = flag.Uint64("limit", 0, "max items to read")
+	fRate     = flag.Uint64("rate", 0, "max items to forward per interval")
+	fInterval = flag.Duration("interval", time.Second, "rate limit interval")
+	fURL      = flag.String("url", "", "HTTP endpoint to post payloads; stdout will use if omit")
+	fTimeout  = flag.Duration("timeout", 10*time.Second, "HTTP request timeout")
+)
+
+func main() {
+	flag.Usage = func() {
+		_, _ = fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] file|dir...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if err := run(); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	var files []string
+	for _, arg := range flag.Args() {
+		fi, err := os.Stat(arg)
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+		list, err := dlq.ListFiles(arg, "")
+		if err != nil {
+			return err
+		}
+		files = append(files, list...)
+	}
+	r, err := dlq.NewReader(dlq.JSONCodec{}, files...)
+	if err != nil {
+		flag.Usage()
+		return err
+	}
+	defer func() { _ = r.Close() }()
+
+	conf := queue.RedriveConfig{
+		Source:       r,
+		Limit:        *fLimit,
+		DryRun:       *fDryRun,
+		RateLimit:    *fRate,
+		RateInterval: *fInterval,
+	}
+	if len(*fReasons) > 0 {
+		for _, s := range strings.Split(*fReasons, ",") {
+			reason, err := dlq.ParseReason(strings.TrimSpace(s))
+			if err != nil {
+				return fmt.Errorf("%w: %s", err, s)
+			}
+			conf.Reasons = append(conf.Reasons, reason)
+		}
+	}
+	if len(*fURL) > 0 {
+		conf.Target = &httpTarget{url: *fURL, cli: &http.Client{Timeout: *fTimeout}}
+	} else {
+		conf.Target = &writerTarget{w: os.Stdout}
+	}
+
+	rd, err := queue.NewRedriver(conf)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	stats, err := rd.Run(ctx)
+	_, _ = fmt.Fprintf(os.Stderr, "read %d, matched %d, moved %d, skipped %d\n",
+		stats.Read, stats.Matched, stats.Moved, stats.Skipped)
+	if err == nil {
+		err = r.Err()
+	}
+	return err
+}
+
+// Target that prints payloads as JSON lines.
+type writerTarget struct {
+	w io.Writer
+}
+
+func (t *writerTarget) Enqueue(x any) error {
+	p, err := json.Marshal(x)
+	if err != nil {
+		return err
+	}
+	_, err = t.w.Write(append(p, '\n'))
+	return err
+}
+
+// Target that posts payloads to HTTP endpoint.
+type httpTarget struct {
+	url string
+	cli *http.Client
+}
+
+func (t *httpTarget) Enqueue(x any) error {
+	p, err := json.Marshal(x)
+	if err != nil {
+		return err
+	}
+	resp, err := t.cli.Post(t.url, "application/json", bytes.NewReader(p))
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
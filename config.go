@@ -24,6 +24,9 @@ const (
 
 // Config describes queue properties and behavior.
 type Config struct {
+	// Name of the queue.
+	// Uses as "queue" field of log records.
+	Name string
 	// Queue capacity.
 	// Mandatory param if QoS config omitted. QoS (if provided) summing capacity will overwrite this field.
 	Capacity uint64
@@ -84,9 +87,18 @@ type Config struct {
 	// If this param omit defaultSleepInterval (5 seconds) will use instead.
 	SleepInterval time.Duration
 
+	// Balancer calculates target number of active workers in range [WorkersMin...WorkersMax].
+	// If this param omit FullnessBalancer will use instead.
+	// See balancer.go and balancer/ package.
+	Balancer Balancer
+
 	// Schedule contains base params (like workers min/max and factors) for specific time ranges.
 	// See schedule.go for usage examples.
 	Schedule *Schedule
+	// RampInterval indicates how long workers bounds (min/max) will converge to the new values after schedule range
+	// switch. Bounds change linearly during that interval, excess workers stop gracefully after finishing current item.
+	// If this param omit bounds will switch immediately.
+	RampInterval time.Duration
 
 	// Worker represents queue worker.
 	// Mandatory param.
@@ -101,11 +113,39 @@ type Config struct {
 	DeadlineToDLQ bool
 	// LeakDirection indicates queue side to leak items (rear or front).
 	LeakDirection LeakDirection
+	// DLQRetryTimeout enables blocking retry of failed DLQ enqueue. Retry attempts repeat each DLQRetryInterval during
+	// that timeout. Retry applies only to items failed in workers and breaks on force close. Leaked items and items
+	// dropped on force close never retry.
+	// If this param omit failed item forwards to DLQSecondary (or counts as lost) immediately.
+	DLQRetryTimeout time.Duration
+	// DLQRetryInterval is an interval between DLQ enqueue attempts.
+	// If this param omit defaultDLQRetryInterval (10ms) will use instead.
+	DLQRetryInterval time.Duration
+	// DLQSecondary is a fallback DLQ to catch items that primary DLQ rejects (after all retries).
+	// If this param omit rejected items will count as lost.
+	DLQSecondary Enqueuer
+	// DLQErrorHandler calls when item finally fails to put to DLQ (considering retries and DLQSecondary) and counts as
+	// lost.
+	DLQErrorHandler func(x any, reason DLQReason, err error)
 	// FrontLeakAttempts indicates how many times queue may be shifted to free up space for new rear item.
 	// On limit overflow rear direction will use by fallback.
 	// Low values required.
 	// If this param omit defaultFrontLeakAttempts (5) will use instead.
 	FrontLeakAttempts uint32
+	// AQM enables active queue management: items drop to DLQ before queue becomes full according policy.
+	// Works only with non-empty DLQ. See aqm.go and aqm/ package for builtin policies.
+	AQM AQM
+	// Overflow is a secondary queue (larger and slower, eg: disk-backed) to spill items when queue is full instead of
+	// leaking them to DLQ. Spilled items return to the queue by background mover when queue fullness rate drops below
+	// OverflowThreshold. Items that overflow queue rejects leak to DLQ as usual.
+	// Works only with non-empty DLQ.
+	Overflow OverflowQueue
+	// OverflowThreshold indicates queue fullness rate to return spilled items from overflow queue.
+	// If this param omit defaultOverflowThreshold (0.5) will use instead.
+	OverflowThreshold float32
+	// OverflowInterval is an interval of overflow queue checks by background mover.
+	// If this param omit defaultOverflowInterval (100ms) will use instead.
+	OverflowInterval time.Duration
 
 	// DelayInterval between item enqueue and processing.
 	// Settings this param enables delayed execution (DE) feature.
@@ -118,6 +158,21 @@ type Config struct {
 	// The opposite param to DelayInterval.
 	DeadlineInterval time.Duration
 
+	// Breaker enables circuit breaker feature.
+	// While breaker is open workers pause consumption and queue moves to StatusThrottle.
+	// See breaker.go for details.
+	Breaker *BreakerConfig
+
+	// RateLimit limits the number of items that workers may take from the queue per RateInterval.
+	// Limit shares among all workers. Setting this param enables rate limiting (token bucket) feature.
+	RateLimit uint64
+	// RateInterval is a period of RateLimit.
+	// If this param omit defaultRateInterval (1 second) will use instead.
+	RateInterval time.Duration
+	// RateBurst indicates how many items may be taken at once above the RateLimit (token bucket size).
+	// If this param omit 1 will use instead.
+	RateBurst uint64
+
 	// Clock represents clock keeper.
 	// If this param omit nativeClock will use instead (see clock.go).
 	Clock Clock
@@ -126,7 +181,22 @@ type Config struct {
 	MetricsWriter MetricsWriter
 
 	// Logger handler.
+	// Deprecated: use StructuredLogger instead.
 	Logger Logger
+	// StructuredLogger handler. Takes precedence over Logger param.
+	// *slog.Logger may be used directly, see also NewHandlerLogger.
+	StructuredLogger StructuredLogger
+
+	// Tracer enables distributed tracing: captures producer's context on enqueue and emits spans for queue wait,
+	// processing attempts, retries and DLQ routing. See tracer.go for details.
+	Tracer Tracer
+
+	// Observer receives lifecycle events (leaks, fails, deadlines, workers status changes, schedule switches and
+	// throttling). See observer.go for details.
+	Observer Observer
+	// Size of observer's events buffer. Events that don't fit the buffer drop.
+	// If this param omit defaultObserverBuffer (1024) will use instead.
+	ObserverBuffer uint
 }
 
 // Copy copies config instance to protect queue from changing params after start.
@@ -139,5 +209,9 @@ func (c *Config) Copy() *Config {
 	if c.QoS != nil {
 		cpy.QoS = c.QoS.Copy()
 	}
+	if c.Breaker != nil {
+		b := *c.Breaker
+		cpy.Breaker = &b
+	}
 	return &cpy
 }
@@ -0,0 +1,238 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/koykov/queue"
+	"github.com/koykov/queue/aqm"
+	"github.com/koykov/queue/qos"
+)
+
+// Build makes queue config using spec params and registry components.
+// Params not covered by spec (clock, metrics writer, logger, balancer) may be set to the result config after build.
+func (s *Spec) Build(reg *Registry) (*queue.Config, error) {
+	if reg == nil {
+		return nil, ErrNoRegistry
+	}
+	c := &queue.Config{
+		Capacity:              s.Capacity,
+		Name:                  s.Name,
+		Streams:               s.Streams,
+		Workers:               s.Workers,
+		WorkersMin:            s.WorkersMin,
+		WorkersMax:            s.WorkersMax,
+		WakeupFactor:          s.WakeupFactor,
+		SleepFactor:           s.SleepFactor,
+		SleepThreshold:        s.SleepThreshold,
+		SleepInterval:         time.Duration(s.SleepInterval),
+		HeartbeatInterval:     time.Duration(s.HeartbeatInterval),
+		ForceCalibrationLimit: s.ForceCalibrationLimit,
+		RampInterval:          time.Duration(s.RampInterval),
+		MaxRetries:            s.MaxRetries,
+		RetryInterval:         time.Duration(s.RetryInterval),
+		DelayInterval:         time.Duration(s.DelayInterval),
+		DeadlineInterval:      time.Duration(s.DeadlineInterval),
+		RateLimit:             s.RateLimit,
+		RateInterval:          time.Duration(s.RateInterval),
+		RateBurst:             s.RateBurst,
+		FailToDLQ:             s.FailToDLQ,
+		DeadlineToDLQ:         s.DeadlineToDLQ,
+		DLQRetryTimeout:       time.Duration(s.DLQRetryTimeout),
+		DLQRetryInterval:      time.Duration(s.DLQRetryInterval),
+		FrontLeakAttempts:     s.FrontLeakAttempts,
+		OverflowThreshold:     s.OverflowThreshold,
+		OverflowInterval:      time.Duration(s.OverflowInterval),
+	}
+	var (
+		ok  bool
+		err error
+	)
+
+	if len(s.Worker) > 0 {
+		if c.Worker, ok = reg.workers[s.Worker]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownWorker, s.Worker)
+		}
+	}
+	if len(s.DLQ) > 0 {
+		if c.DLQ, ok = reg.dlqs[s.DLQ]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownDLQ, s.DLQ)
+		}
+	}
+	if len(s.DLQSecondary) > 0 {
+		if c.DLQSecondary, ok = reg.dlqs[s.DLQSecondary]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownDLQ, s.DLQSecondary)
+		}
+	}
+	if len(s.Overflow) > 0 {
+		if c.Overflow, ok = reg.overflows[s.Overflow]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownOverflow, s.Overflow)
+		}
+	}
+	if c.LeakDirection, err = leakDirection(s.LeakDirection); err != nil {
+		return nil, err
+	}
+	if s.Backoff != nil && len(s.Backoff.Name) > 0 {
+		fn, ok := reg.backoffs[s.Backoff.Name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownBackoff, s.Backoff.Name)
+		}
+		c.Backoff = fn(*s.Backoff)
+	}
+	if s.Jitter != nil && len(s.Jitter.Name) > 0 {
+		fn, ok := reg.jitters[s.Jitter.Name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownJitter, s.Jitter.Name)
+		}
+		c.Jitter = fn(*s.Jitter)
+	}
+	if a := s.AQM; a != nil {
+		switch strings.ToLower(a.Policy) {
+		case "red":
+			c.AQM = &aqm.RED{
+				MinThreshold:   a.MinThreshold,
+				MaxThreshold:   a.MaxThreshold,
+				MaxProbability: a.MaxProbability,
+				Weight:         a.Weight,
+			}
+		case "codel":
+			c.AQM = &aqm.CoDel{Target: time.Duration(a.Target), Interval: time.Duration(a.Interval)}
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrUnknownAQM, a.Policy)
+		}
+	}
+	if b := s.Breaker; b != nil {
+		c.Breaker = &queue.BreakerConfig{
+			FailureThreshold: b.FailureThreshold,
+			FailureRate:      b.FailureRate,
+			MinRequests:      b.MinRequests,
+			Window:           time.Duration(b.Window),
+			OpenInterval:     time.Duration(b.OpenInterval),
+			HalfOpenRequests: b.HalfOpenRequests,
+		}
+	}
+	if s.QoS != nil {
+		if c.QoS, err = s.QoS.build(reg); err != nil {
+			return nil, err
+		}
+	}
+	if s.Schedule != nil {
+		if c.Schedule, err = s.Schedule.build(); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (s *QoSSpec) build(reg *Registry) (*qos.Config, error) {
+	var algo qos.Algo
+	switch strings.ToLower(s.Algo) {
+	case "pq", "":
+		algo = qos.PQ
+	case "rr":
+		algo = qos.RR
+	case "wrr":
+		algo = qos.WRR
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAlgo, s.Algo)
+	}
+	var eviction qos.Eviction
+	switch strings.ToLower(s.Eviction) {
+	case "subq", "":
+		eviction = qos.EvictionSubq
+	case "priority":
+		eviction = qos.EvictionPriority
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownEviction, s.Eviction)
+	}
+	eval, ok := reg.evals[s.Evaluator]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownEvaluator, s.Evaluator)
+	}
+	c := qos.New(algo, eval).SetEviction(eviction)
+	if s.Egress.Capacity > 0 {
+		c.SetEgressCapacity(s.Egress.Capacity)
+	}
+	if s.Egress.Streams > 0 {
+		c.SetEgressStreams(s.Egress.Streams)
+	}
+	c.SetEgressWorkers(s.Egress.Workers).
+		SetEgressIdleThreshold(s.Egress.IdleThreshold).
+		SetEgressIdleTimeout(time.Duration(s.Egress.IdleTimeout))
+	for i := 0; i < len(s.Queues); i++ {
+		q := &s.Queues[i]
+		var dp *qos.DropProfile
+		if p := q.DropProfile; p != nil {
+			dp = &qos.DropProfile{MinThreshold: p.MinThreshold, MaxThreshold: p.MaxThreshold, MaxProbability: p.MaxProbability}
+		}
+		c.AddQueue(qos.Queue{
+			Name:          q.Name,
+			Capacity:      q.Capacity,
+			Weight:        q.Weight,
+			IngressWeight: q.IngressWeight,
+			EgressWeight:  q.EgressWeight,
+			RateLimit:     q.RateLimit,
+			RateInterval:  time.Duration(q.RateInterval),
+			RateBurst:     q.RateBurst,
+			Protected:     q.Protected,
+			DropProfile:   dp,
+		})
+	}
+	return c, nil
+}
+
+func (s *ScheduleSpec) build() (*queue.Schedule, error) {
+	sched := queue.NewSchedule()
+	if len(s.Location) > 0 {
+		loc, err := time.LoadLocation(s.Location)
+		if err != nil {
+			return nil, err
+		}
+		sched.SetLocation(loc)
+	}
+	for i := 0; i < len(s.Ranges); i++ {
+		r := &s.Ranges[i]
+		params := queue.ScheduleParams{
+			WorkersMin:   r.WorkersMin,
+			WorkersMax:   r.WorkersMax,
+			WakeupFactor: r.WakeupFactor,
+			SleepFactor:  r.SleepFactor,
+		}
+		var err error
+		if o := r.Overlay; o != nil {
+			overlay := queue.ScheduleOverlay{
+				RetryInterval:    time.Duration(o.RetryInterval),
+				DeadlineInterval: time.Duration(o.DeadlineInterval),
+				RateLimit:        o.RateLimit,
+				RateInterval:     time.Duration(o.RateInterval),
+				RateBurst:        o.RateBurst,
+				QoSWeights:       o.QoSWeights,
+			}
+			if len(o.LeakDirection) > 0 {
+				var ld queue.LeakDirection
+				if ld, err = leakDirection(o.LeakDirection); err != nil {
+					return nil, err
+				}
+				overlay.LeakDirection = &ld
+			}
+			err = sched.AddRangeOverlay(r.Range, params, overlay)
+		} else {
+			err = sched.AddRange(r.Range, params)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("range %q: %w", r.Range, err)
+		}
+	}
+	return sched, nil
+}
+
+func leakDirection(raw string) (queue.LeakDirection, error) {
+	switch strings.ToLower(raw) {
+	case "rear", "":
+		return queue.LeakDirectionRear, nil
+	case "front":
+		return queue.LeakDirectionFront, nil
+	}
+	return 0, fmt.Errorf("%w: %s", ErrUnknownLeak, raw)
+}
@@ -0,0 +1,192 @@
+package config
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/koykov/queue"
+	"github.com/koykov/queue/aqm"
+	"github.com/koykov/queue/backoff"
+	"github.com/koykov/queue/qos"
+)
+
+type testWorker struct{}
+
+func (testWorker) Do(_ any) error { return nil }
+
+const (
+	testJSON = `{
+	"workers_min": 2,
+	"workers_max": 8,
+	"heartbeat_interval": "100ms",
+	"worker": "nop",
+	"max_retries": 3,
+	"retry_interval": "1s",
+	"backoff": {"name": "exponential"},
+	"jitter": {"name": "decorrelated", "min": "100ms", "max": "5s"},
+	"dlq": "dummy",
+	"fail_to_dlq": true,
+	"leak_direction": "front",
+	"qos": {
+		"algo": "pq",
+		"evaluator": "weighted",
+		"eviction": "priority",
+		"queues": [
+			{"name": "high", "capacity": 100, "weight": 300, "protected": true},
+			{"name": "low", "capacity": 200, "weight": 100}
+		]
+	},
+	"schedule": {
+		"location": "UTC",
+		"ranges": [
+			{"range": "mon-fri 09:00-18:00", "workers_min": 4, "workers_max": 16},
+			{"range": "22:00-06:00", "workers_max": 2, "overlay": {"rate_limit": 10, "leak_direction": "rear"}}
+		]
+	}
+}`
+	testYAML = `
+workers_min: 2
+workers_max: 8
+heartbeat_interval: 100ms
+worker: nop
+max_retries: 3
+retry_interval: 1s
+backoff:
+  name: exponential
+jitter:
+  name: decorrelated
+  min: 100ms
+  max: 5s
+dlq: dummy
+fail_to_dlq: true
+leak_direction: front
+qos:
+  algo: pq
+  evaluator: weighted
+  eviction: priority
+  queues:
+    - {name: high, capacity: 100, weight: 300, protected: true}
+    - {name: low, capacity: 200, weight: 100}
+schedule:
+  location: UTC
+  ranges:
+    - {range: "mon-fri 09:00-18:00", workers_min: 4, workers_max: 16}
+    - range: "22:00-06:00"
+      workers_max: 2
+      overlay: {rate_limit: 10, leak_direction: rear}
+`
+)
+
+var testEnv = map[string]string{
+	"Q_WORKERS_MIN":        "2",
+	"Q_WORKERS_MAX":        "8",
+	"Q_HEARTBEAT_INTERVAL": "100ms",
+	"Q_WORKER":             "nop",
+	"Q_MAX_RETRIES":        "3",
+	"Q_RETRY_INTERVAL":     "1s",
+	"Q_BACKOFF":            `{"name":"exponential"}`,
+	"Q_JITTER":             `{"name":"decorrelated","min":"100ms","max":"5s"}`,
+	"Q_DLQ":                "dummy",
+	"Q_FAIL_TO_DLQ":        "true",
+	"Q_LEAK_DIRECTION":     "front",
+	"Q_QOS": `{"algo":"pq","evaluator":"weighted","eviction":"priority",` +
+		`"queues":[{"name":"high","capacity":100,"weight":300,"protected":true},` +
+		`{"name":"low","capacity":200,"weight":100}]}`,
+	"Q_SCHEDULE": `{"location":"UTC","ranges":[{"range":"mon-fri 09:00-18:00","workers_min":4,"workers_max":16},` +
+		`{"range":"22:00-06:00","workers_max":2,"overlay":{"rate_limit":10,"leak_direction":"rear"}}]}`,
+}
+
+func testRegistry() *Registry {
+	return NewRegistry().
+		RegisterWorker("nop", testWorker{}).
+		RegisterDLQ("dummy", queue.DummyDLQ{}).
+		RegisterEvaluator("weighted", qos.DummyPriorityEvaluator{})
+}
+
+func TestSpec(t *testing.T) {
+	check := func(t *testing.T, s *Spec, err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := s.Build(testRegistry())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.WorkersMin != 2 || c.WorkersMax != 8 || c.MaxRetries != 3 || c.RetryInterval != time.Second ||
+			c.HeartbeatInterval != time.Millisecond*100 || !c.FailToDLQ || c.LeakDirection != queue.LeakDirectionFront {
+			t.Errorf("params mismatch: %+v", c)
+		}
+		if _, ok := c.Backoff.(backoff.Exponential); !ok {
+			t.Errorf("backoff mismatch: %T", c.Backoff)
+		}
+		if c.QoS == nil || c.QoS.Algo != qos.PQ || c.QoS.Eviction != qos.EvictionPriority || len(c.QoS.Queues) != 2 ||
+			c.QoS.Queues[1].Name != "low" || !c.QoS.Queues[0].Protected {
+			t.Errorf("qos mismatch: %+v", c.QoS)
+		}
+		if c.Schedule == nil || c.Schedule.WorkersMaxDaily() != 16 {
+			t.Error("schedule mismatch")
+		}
+		q, err := queue.New(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = q.ForceClose()
+	}
+	t.Run("json", func(t *testing.T) {
+		s, err := FromJSON([]byte(testJSON))
+		check(t, s, err)
+	})
+	t.Run("yaml", func(t *testing.T) {
+		s, err := FromYAML([]byte(testYAML))
+		check(t, s, err)
+	})
+	t.Run("env", func(t *testing.T) {
+		s, err := FromLookup("Q", func(key string) (string, bool) {
+			v, ok := testEnv[key]
+			return v, ok
+		})
+		check(t, s, err)
+	})
+	t.Run("aqm", func(t *testing.T) {
+		s := Spec{Capacity: 10, Worker: "nop", DLQ: "dummy", AQM: &AQMSpec{Policy: "codel", Target: Duration(time.Millisecond)}}
+		c, err := s.Build(testRegistry())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p, ok := c.AQM.(*aqm.CoDel); !ok || p.Target != time.Millisecond {
+			t.Errorf("AQM mismatch: %+v", c.AQM)
+		}
+		s.AQM.Policy = "foobar"
+		if _, err = s.Build(testRegistry()); !errors.Is(err, ErrUnknownAQM) {
+			t.Errorf("need ErrUnknownAQM, got %v", err)
+		}
+	})
+	t.Run("unknown", func(t *testing.T) {
+		s := Spec{Capacity: 10, Worker: "foobar"}
+		if _, err := s.Build(testRegistry()); !errors.Is(err, ErrUnknownWorker) {
+			t.Errorf("need ErrUnknownWorker, got %v", err)
+		}
+		s = Spec{Capacity: 10, Worker: "nop", Backoff: &BackoffSpec{Name: "foobar"}}
+		if _, err := s.Build(testRegistry()); !errors.Is(err, ErrUnknownBackoff) {
+			t.Errorf("need ErrUnknownBackoff, got %v", err)
+		}
+		if _, err := FromLookup("Q", func(key string) (string, bool) { return "foo", key == "Q_CAPACITY" }); !errors.Is(err, ErrBadEnv) {
+			t.Errorf("need ErrBadEnv, got %v", err)
+		}
+	})
+	t.Run("duration", func(t *testing.T) {
+		var d Duration
+		for _, src := range []string{`"1.5s"`, `0`, `"0"`} {
+			if err := d.UnmarshalJSON([]byte(src)); err != nil {
+				t.Errorf("%s: unexpected error %v", src, err)
+			}
+		}
+		// Unitless integers are ambiguous.
+		for _, src := range []string{`100`, `"100"`} {
+			if err := d.UnmarshalJSON([]byte(src)); !errors.Is(err, ErrBadDuration) {
+				t.Errorf("%s: need ErrBadDuration, got %v", src, err)
+			}
+		}
+	})
+}
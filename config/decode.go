@@ -0,0 +1,116 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FromJSON decodes spec from JSON data.
+func FromJSON(data []byte) (*Spec, error) {
+	var s Spec
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// FromYAML decodes spec from YAML data.
+func FromYAML(data []byte) (*Spec, error) {
+	var s Spec
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// FromFile decodes spec from file. Format detects by file extension (.json, .yaml or .yml).
+func FromFile(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FromJSON(data)
+	case ".yaml", ".yml":
+		return FromYAML(data)
+	}
+	return nil, ErrUnknownFormat
+}
+
+// FromEnv decodes spec from environment variables.
+//
+// Variable names compose of prefix and field name in upper snake case, eg: QUEUE_CAPACITY, QUEUE_WORKERS_MAX,
+// QUEUE_RETRY_INTERVAL. Durations specify in time.ParseDuration format. Composite fields (backoff, jitter, breaker, qos
+// and schedule) specify as JSON, eg: QUEUE_BACKOFF='{"name":"exponential"}'.
+// Empty prefix means no prefix.
+func FromEnv(prefix string) (*Spec, error) {
+	return FromLookup(prefix, os.LookupEnv)
+}
+
+// FromLookup decodes spec using lookup function.
+// See FromEnv for variables format.
+func FromLookup(prefix string, lookup func(key string) (string, bool)) (*Spec, error) {
+	var s Spec
+	if len(prefix) > 0 && !strings.HasSuffix(prefix, "_") {
+		prefix += "_"
+	}
+	v := reflect.ValueOf(&s).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("env")
+		if len(tag) == 0 {
+			continue
+		}
+		key := prefix + tag
+		raw, ok := lookup(key)
+		if !ok {
+			continue
+		}
+		if err := setEnv(v.Field(i), raw); err != nil {
+			return nil, fmt.Errorf("%w %s: %s", ErrBadEnv, key, err.Error())
+		}
+	}
+	return &s, nil
+}
+
+func setEnv(v reflect.Value, raw string) (err error) {
+	if tu, ok := v.Addr().Interface().(interface{ UnmarshalText([]byte) error }); ok {
+		return tu.UnmarshalText([]byte(raw))
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		var b bool
+		if b, err = strconv.ParseBool(raw); err == nil {
+			v.SetBool(b)
+		}
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var u uint64
+		if u, err = strconv.ParseUint(raw, 10, v.Type().Bits()); err == nil {
+			v.SetUint(u)
+		}
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		if f, err = strconv.ParseFloat(raw, v.Type().Bits()); err == nil {
+			v.SetFloat(f)
+		}
+	default:
+		// Composite values.
+		err = json.Unmarshal([]byte(raw), v.Addr().Interface())
+	}
+	return
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"encoding/json"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Duration is a time.Duration that decodes from string in time.ParseDuration format (eg "1.5s").
+// Unit is required, so unitless numbers (except zero) are rejected to avoid ambiguity of seconds vs nanoseconds.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(p []byte) error {
+	var s string
+	if err := json.Unmarshal(p, &s); err != nil {
+		// Number literal.
+		return d.UnmarshalText(p)
+	}
+	return d.UnmarshalText([]byte(s))
+}
+
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	return d.UnmarshalText([]byte(node.Value))
+}
+
+func (d *Duration) UnmarshalText(p []byte) error {
+	if len(p) == 0 {
+		*d = 0
+		return nil
+	}
+	x, err := time.ParseDuration(string(p))
+	if err != nil {
+		return ErrBadDuration
+	}
+	*d = Duration(x)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d Duration) MarshalYAML() (any, error) {
+	return time.Duration(d).String(), nil
+}
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
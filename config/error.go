@@ -0,0 +1,20 @@
+package config
+
+import "errors"
+
+var (
+	ErrNoRegistry       = errors.New("no registry provided")
+	ErrUnknownFormat    = errors.New("unknown config format")
+	ErrBadDuration      = errors.New("bad duration provided")
+	ErrBadEnv           = errors.New("bad environment variable value")
+	ErrUnknownWorker    = errors.New("unknown worker")
+	ErrUnknownDLQ       = errors.New("unknown DLQ")
+	ErrUnknownOverflow  = errors.New("unknown overflow queue")
+	ErrUnknownEvaluator = errors.New("unknown priority evaluator")
+	ErrUnknownBackoff   = errors.New("unknown backoff")
+	ErrUnknownJitter    = errors.New("unknown jitter")
+	ErrUnknownAlgo      = errors.New("unknown QoS algorithm")
+	ErrUnknownEviction  = errors.New("unknown QoS eviction policy")
+	ErrUnknownLeak      = errors.New("unknown leak direction")
+	ErrUnknownAQM       = errors.New("unknown AQM policy")
+)
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"time"
+
+	"github.com/koykov/queue"
+	"github.com/koykov/queue/backoff"
+	"github.com/koykov/queue/jitter"
+	"github.com/koykov/queue/qos"
+	"github.com/koykov/queue/rng"
+)
+
+// BackoffFactory makes backoff instance using spec params.
+type BackoffFactory func(spec BackoffSpec) queue.Backoff
+
+// JitterFactory makes jitter instance using spec params.
+type JitterFactory func(spec JitterSpec) queue.Jitter
+
+// Registry maps names used in Spec to component instances.
+//
+// Builtin backoffs and jitters registered by default, but may be overwritten.
+type Registry struct {
+	workers   map[string]queue.Worker
+	dlqs      map[string]queue.Enqueuer
+	overflows map[string]queue.OverflowQueue
+	evals     map[string]qos.PriorityEvaluator
+	backoffs  map[string]BackoffFactory
+	jitters   map[string]JitterFactory
+}
+
+// NewRegistry makes new registry with builtin backoffs and jitters.
+func NewRegistry() *Registry {
+	r := &Registry{
+		workers:   make(map[string]queue.Worker),
+		dlqs:      make(map[string]queue.Enqueuer),
+		overflows: make(map[string]queue.OverflowQueue),
+		evals:     make(map[string]qos.PriorityEvaluator),
+		backoffs:  make(map[string]BackoffFactory),
+		jitters:   make(map[string]JitterFactory),
+	}
+	r.RegisterBackoff("linear", func(_ BackoffSpec) queue.Backoff { return backoff.Linear{} }).
+		RegisterBackoff("exponential", func(_ BackoffSpec) queue.Backoff { return backoff.Exponential{} }).
+		RegisterBackoff("fibonacci", func(_ BackoffSpec) queue.Backoff { return backoff.Fibonacci{} }).
+		RegisterBackoff("logarithmic", func(_ BackoffSpec) queue.Backoff { return backoff.Logarithmic{} }).
+		RegisterBackoff("polynomial", func(spec BackoffSpec) queue.Backoff { return backoff.Polynomial{K: spec.K} }).
+		RegisterBackoff("quadratic", func(_ BackoffSpec) queue.Backoff { return backoff.Quadratic{} }).
+		RegisterBackoff("random", func(_ BackoffSpec) queue.Backoff { return &backoff.Random{RNG: &rng.Pool{}} })
+	r.RegisterJitter("full", func(_ JitterSpec) queue.Jitter { return &jitter.Full{} }).
+		RegisterJitter("half", func(_ JitterSpec) queue.Jitter { return &jitter.Half{} }).
+		RegisterJitter("decorrelated", func(spec JitterSpec) queue.Jitter {
+			return &jitter.Decorrelated{Min: time.Duration(spec.Min), Max: time.Duration(spec.Max)}
+		})
+	return r
+}
+
+// RegisterWorker registers worker under given name.
+func (r *Registry) RegisterWorker(name string, worker queue.Worker) *Registry {
+	r.workers[name] = worker
+	return r
+}
+
+// RegisterDLQ registers dead letter queue under given name.
+func (r *Registry) RegisterDLQ(name string, dlq queue.Enqueuer) *Registry {
+	r.dlqs[name] = dlq
+	return r
+}
+
+// RegisterOverflow registers overflow queue under given name.
+func (r *Registry) RegisterOverflow(name string, oq queue.OverflowQueue) *Registry {
+	r.overflows[name] = oq
+	return r
+}
+
+// RegisterEvaluator registers QoS priority evaluator under given name.
+func (r *Registry) RegisterEvaluator(name string, eval qos.PriorityEvaluator) *Registry {
+	r.evals[name] = eval
+	return r
+}
+
+// RegisterBackoff registers backoff factory under given name.
+func (r *Registry) RegisterBackoff(name string, fn BackoffFactory) *Registry {
+	r.backoffs[name] = fn
+	return r
+}
+
+// RegisterJitter registers jitter factory under given name.
+func (r *Registry) RegisterJitter(name string, fn JitterFactory) *Registry {
+	r.jitters[name] = fn
+	return r
+}
@@ -0,0 +1,189 @@
+package config
+
+// Spec is a serializable description of queue config.
+//
+// Spec contains only plain values, so it may be decoded from JSON, YAML or environment variables. Components (workers,
+// DLQs, priority evaluators, backoffs and jitters) specify by names and resolve using Registry (see registry.go).
+type Spec struct {
+	// Queue name. See queue.Config.Name.
+	Name string `json:"name" yaml:"name" env:"NAME"`
+	// Queue capacity. See queue.Config.Capacity.
+	Capacity uint64 `json:"capacity" yaml:"capacity" env:"CAPACITY"`
+	// Number of sub-channels. See queue.Config.Streams.
+	Streams uint32 `json:"streams" yaml:"streams" env:"STREAMS"`
+
+	// Worker name in the registry.
+	// Mandatory param.
+	Worker string `json:"worker" yaml:"worker" env:"WORKER"`
+	// Workers number params. See queue.Config.Workers, queue.Config.WorkersMin and queue.Config.WorkersMax.
+	Workers    uint32 `json:"workers" yaml:"workers" env:"WORKERS"`
+	WorkersMin uint32 `json:"workers_min" yaml:"workers_min" env:"WORKERS_MIN"`
+	WorkersMax uint32 `json:"workers_max" yaml:"workers_max" env:"WORKERS_MAX"`
+	// Balancing params. See corresponding queue.Config params.
+	WakeupFactor          float32  `json:"wakeup_factor" yaml:"wakeup_factor" env:"WAKEUP_FACTOR"`
+	SleepFactor           float32  `json:"sleep_factor" yaml:"sleep_factor" env:"SLEEP_FACTOR"`
+	SleepThreshold        uint32   `json:"sleep_threshold" yaml:"sleep_threshold" env:"SLEEP_THRESHOLD"`
+	SleepInterval         Duration `json:"sleep_interval" yaml:"sleep_interval" env:"SLEEP_INTERVAL"`
+	HeartbeatInterval     Duration `json:"heartbeat_interval" yaml:"heartbeat_interval" env:"HEARTBEAT_INTERVAL"`
+	ForceCalibrationLimit uint32   `json:"force_calibration_limit" yaml:"force_calibration_limit" env:"FORCE_CALIBRATION_LIMIT"`
+	RampInterval          Duration `json:"ramp_interval" yaml:"ramp_interval" env:"RAMP_INTERVAL"`
+
+	// Retry params. See corresponding queue.Config params.
+	MaxRetries    uint32       `json:"max_retries" yaml:"max_retries" env:"MAX_RETRIES"`
+	RetryInterval Duration     `json:"retry_interval" yaml:"retry_interval" env:"RETRY_INTERVAL"`
+	Backoff       *BackoffSpec `json:"backoff" yaml:"backoff" env:"BACKOFF"`
+	Jitter        *JitterSpec  `json:"jitter" yaml:"jitter" env:"JITTER"`
+
+	// Delayed execution and deadline params. See corresponding queue.Config params.
+	DelayInterval    Duration `json:"delay_interval" yaml:"delay_interval" env:"DELAY_INTERVAL"`
+	DeadlineInterval Duration `json:"deadline_interval" yaml:"deadline_interval" env:"DEADLINE_INTERVAL"`
+
+	// Rate limit params. See corresponding queue.Config params.
+	RateLimit    uint64   `json:"rate_limit" yaml:"rate_limit" env:"RATE_LIMIT"`
+	RateInterval Duration `json:"rate_interval" yaml:"rate_interval" env:"RATE_INTERVAL"`
+	RateBurst    uint64   `json:"rate_burst" yaml:"rate_burst" env:"RATE_BURST"`
+
+	// Leak params.
+	// DLQ is a name of dead letter queue in the registry.
+	DLQ           string `json:"dlq" yaml:"dlq" env:"DLQ"`
+	FailToDLQ     bool   `json:"fail_to_dlq" yaml:"fail_to_dlq" env:"FAIL_TO_DLQ"`
+	DeadlineToDLQ bool   `json:"deadline_to_dlq" yaml:"deadline_to_dlq" env:"DEADLINE_TO_DLQ"`
+	// DLQ failures fallback params. DLQSecondary is a name of fallback dead letter queue in the registry.
+	DLQSecondary     string   `json:"dlq_secondary" yaml:"dlq_secondary" env:"DLQ_SECONDARY"`
+	DLQRetryTimeout  Duration `json:"dlq_retry_timeout" yaml:"dlq_retry_timeout" env:"DLQ_RETRY_TIMEOUT"`
+	DLQRetryInterval Duration `json:"dlq_retry_interval" yaml:"dlq_retry_interval" env:"DLQ_RETRY_INTERVAL"`
+	// LeakDirection may be "rear" or "front". Empty value means rear direction.
+	LeakDirection     string `json:"leak_direction" yaml:"leak_direction" env:"LEAK_DIRECTION"`
+	FrontLeakAttempts uint32 `json:"front_leak_attempts" yaml:"front_leak_attempts" env:"FRONT_LEAK_ATTEMPTS"`
+	// Overflow params. Overflow is a name of overflow queue in the registry.
+	Overflow          string   `json:"overflow" yaml:"overflow" env:"OVERFLOW"`
+	OverflowThreshold float32  `json:"overflow_threshold" yaml:"overflow_threshold" env:"OVERFLOW_THRESHOLD"`
+	OverflowInterval  Duration `json:"overflow_interval" yaml:"overflow_interval" env:"OVERFLOW_INTERVAL"`
+
+	// Active queue management params. See aqm package.
+	AQM *AQMSpec `json:"aqm" yaml:"aqm" env:"AQM"`
+
+	// Circuit breaker params. See queue.BreakerConfig.
+	Breaker *BreakerSpec `json:"breaker" yaml:"breaker" env:"BREAKER"`
+	// QoS params. See qos.Config.
+	QoS *QoSSpec `json:"qos" yaml:"qos" env:"QOS"`
+	// Schedule params. See queue.Schedule.
+	Schedule *ScheduleSpec `json:"schedule" yaml:"schedule" env:"SCHEDULE"`
+}
+
+// BackoffSpec describes backoff by name and params.
+type BackoffSpec struct {
+	// Name of backoff in the registry.
+	// Builtin backoffs: linear, exponential, fibonacci, logarithmic, polynomial, quadratic, random.
+	Name string `json:"name" yaml:"name"`
+	// Polynomial constant.
+	K uint64 `json:"k" yaml:"k"`
+}
+
+// JitterSpec describes jitter by name and params.
+type JitterSpec struct {
+	// Name of jitter in the registry.
+	// Builtin jitters: full, half, decorrelated.
+	Name string `json:"name" yaml:"name"`
+	// Decorrelated jitter limits.
+	Min Duration `json:"min" yaml:"min"`
+	Max Duration `json:"max" yaml:"max"`
+}
+
+// AQMSpec describes active queue management policy by name and params.
+type AQMSpec struct {
+	// Policy may be "red" or "codel".
+	Policy string `json:"policy" yaml:"policy"`
+	// RED params. See aqm.RED.
+	MinThreshold   float32 `json:"min_threshold" yaml:"min_threshold"`
+	MaxThreshold   float32 `json:"max_threshold" yaml:"max_threshold"`
+	MaxProbability float32 `json:"max_probability" yaml:"max_probability"`
+	Weight         float32 `json:"weight" yaml:"weight"`
+	// CoDel params. See aqm.CoDel.
+	Target   Duration `json:"target" yaml:"target"`
+	Interval Duration `json:"interval" yaml:"interval"`
+}
+
+// BreakerSpec describes circuit breaker params.
+type BreakerSpec struct {
+	FailureThreshold uint32   `json:"failure_threshold" yaml:"failure_threshold"`
+	FailureRate      float32  `json:"failure_rate" yaml:"failure_rate"`
+	MinRequests      uint32   `json:"min_requests" yaml:"min_requests"`
+	Window           Duration `json:"window" yaml:"window"`
+	OpenInterval     Duration `json:"open_interval" yaml:"open_interval"`
+	HalfOpenRequests uint32   `json:"half_open_requests" yaml:"half_open_requests"`
+}
+
+// QoSSpec describes QoS params.
+type QoSSpec struct {
+	// Algo may be "pq", "rr" or "wrr".
+	Algo string `json:"algo" yaml:"algo"`
+	// Evaluator is a name of priority evaluator in the registry.
+	Evaluator string      `json:"evaluator" yaml:"evaluator"`
+	Egress    EgressSpec  `json:"egress" yaml:"egress"`
+	Queues    []QueueSpec `json:"queues" yaml:"queues"`
+	// Eviction may be "subq" or "priority". Empty value means "subq".
+	Eviction string `json:"eviction" yaml:"eviction"`
+}
+
+// EgressSpec describes QoS egress params.
+type EgressSpec struct {
+	Capacity      uint64   `json:"capacity" yaml:"capacity"`
+	Streams       uint32   `json:"streams" yaml:"streams"`
+	Workers       uint32   `json:"workers" yaml:"workers"`
+	IdleThreshold uint32   `json:"idle_threshold" yaml:"idle_threshold"`
+	IdleTimeout   Duration `json:"idle_timeout" yaml:"idle_timeout"`
+}
+
+// QueueSpec describes QoS sub-queue params.
+type QueueSpec struct {
+	Name          string   `json:"name" yaml:"name"`
+	Capacity      uint64   `json:"capacity" yaml:"capacity"`
+	Weight        uint64   `json:"weight" yaml:"weight"`
+	IngressWeight uint64   `json:"ingress_weight" yaml:"ingress_weight"`
+	EgressWeight  uint64   `json:"egress_weight" yaml:"egress_weight"`
+	RateLimit     uint64   `json:"rate_limit" yaml:"rate_limit"`
+	RateInterval  Duration `json:"rate_interval" yaml:"rate_interval"`
+	RateBurst     uint64   `json:"rate_burst" yaml:"rate_burst"`
+	Protected     bool     `json:"protected" yaml:"protected"`
+	// Drop profile for WRED policy. See qos.DropProfile.
+	DropProfile *DropProfileSpec `json:"drop_profile" yaml:"drop_profile"`
+}
+
+// DropProfileSpec describes QoS sub-queue drop profile.
+type DropProfileSpec struct {
+	MinThreshold   float32 `json:"min_threshold" yaml:"min_threshold"`
+	MaxThreshold   float32 `json:"max_threshold" yaml:"max_threshold"`
+	MaxProbability float32 `json:"max_probability" yaml:"max_probability"`
+}
+
+// ScheduleSpec describes schedule params.
+type ScheduleSpec struct {
+	// Location name in IANA time zone database, eg "Europe/Berlin".
+	// If this param omit process local zone will use instead.
+	Location string `json:"location" yaml:"location"`
+	// Time ranges list.
+	Ranges []RangeSpec `json:"ranges" yaml:"ranges"`
+}
+
+// RangeSpec describes schedule time range.
+type RangeSpec struct {
+	// Range in format of queue.Schedule.AddRange, eg "mon-fri 09:00-18:00".
+	Range        string       `json:"range" yaml:"range"`
+	WorkersMin   uint32       `json:"workers_min" yaml:"workers_min"`
+	WorkersMax   uint32       `json:"workers_max" yaml:"workers_max"`
+	WakeupFactor float32      `json:"wakeup_factor" yaml:"wakeup_factor"`
+	SleepFactor  float32      `json:"sleep_factor" yaml:"sleep_factor"`
+	Overlay      *OverlaySpec `json:"overlay" yaml:"overlay"`
+}
+
+// OverlaySpec describes schedule overlay. See queue.ScheduleOverlay.
+type OverlaySpec struct {
+	RetryInterval    Duration          `json:"retry_interval" yaml:"retry_interval"`
+	DeadlineInterval Duration          `json:"deadline_interval" yaml:"deadline_interval"`
+	RateLimit        uint64            `json:"rate_limit" yaml:"rate_limit"`
+	RateInterval     Duration          `json:"rate_interval" yaml:"rate_interval"`
+	RateBurst        uint64            `json:"rate_burst" yaml:"rate_burst"`
+	LeakDirection    string            `json:"leak_direction" yaml:"leak_direction"`
+	QoSWeights       map[string]uint64 `json:"qos_weights" yaml:"qos_weights"`
+}
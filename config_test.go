@@ -1,9 +1,14 @@
 package queue
 
 import (
+	"errors"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 	"unsafe"
+
+	"github.com/koykov/queue/qos"
 )
 
 func TestConfig(t *testing.T) {
@@ -37,4 +42,67 @@ func TestConfig(t *testing.T) {
 			}
 		}
 	})
+	t.Run("validate", func(t *testing.T) {
+		sched := NewSchedule()
+		_ = sched.AddRangeOverlay("08:00-20:00", ScheduleParams{WorkersMax: 4}, ScheduleOverlay{
+			QoSWeights: map[string]uint64{"high": 100, "foobar": 200},
+		})
+		conf := &Config{
+			WorkersMin:   8,
+			WorkersMax:   4,
+			WakeupFactor: 1.5,
+			SleepFactor:  -.1,
+			FailToDLQ:    true,
+			QoS: qos.New(qos.WRR, qos.DummyPriorityEvaluator{}).
+				AddQueue(qos.Queue{Name: "high", Capacity: 10, Weight: 100}).
+				AddQueue(qos.Queue{Name: "low", IngressWeight: 100}),
+			Schedule: sched,
+		}
+		cpy := *conf.QoS
+		cpy.Queues = append([]qos.Queue(nil), conf.QoS.Queues...)
+
+		err := conf.Validate()
+		var errs ConfigErrors
+		if !errors.As(err, &errs) {
+			t.Fatalf("need ConfigErrors, got %T", err)
+		}
+		type pair struct {
+			field    string
+			severity ConfigSeverity
+		}
+		var got []pair
+		for _, e := range errs {
+			got = append(got, pair{e.Field, e.Severity})
+		}
+		exp := []pair{
+			{"Worker", ConfigFatal},
+			{"WorkersMin", ConfigWarning},
+			{"WakeupFactor", ConfigWarning},
+			{"SleepFactor", ConfigWarning},
+			{"FailToDLQ", ConfigWarning},
+			{"QoS.Queues[1].Capacity", ConfigFatal},
+			{"QoS.Queues[1].EgressWeight", ConfigFatal},
+			{"Schedule[08:00:00.000-20:00:00.000].QoSWeights[foobar]", ConfigFatal},
+		}
+		if !reflect.DeepEqual(got, exp) {
+			t.Errorf("problems mismatch:\nneed %v\ngot  %v", exp, got)
+		}
+		if !errors.Is(err, ErrNoWorker) || !errors.Is(err, qos.ErrNoEgressWeight) || !errors.Is(err, ErrUnknownSubq) {
+			t.Error("errors.Is mismatch")
+		}
+		if !errs.HasFatal() || len(errs.Warnings()) != 4 || len(errs.Fatal()) != 4 {
+			t.Error("severity filter mismatch")
+		}
+		if conf.WakeupFactor != 1.5 || conf.WorkersMin != 8 || !reflect.DeepEqual(*conf.QoS, cpy) {
+			t.Error("config modified")
+		}
+		if err = conf.QoS.Validate(); !errors.Is(err, qos.ErrNoCapacity) || !strings.HasPrefix(err.Error(), "Queues[1].Capacity: ") {
+			t.Errorf("QoS validate mismatch: %v", err)
+		}
+
+		conf = &Config{Capacity: 10, Workers: 2, Worker: nopWorker{}}
+		if err = conf.Validate(); err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+	})
 }
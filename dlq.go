@@ -0,0 +1,120 @@
+package queue
+
+import "time"
+
+// DLQReason indicates why item was sent to DLQ.
+type DLQReason uint8
+
+const (
+	// DLQReasonLeak means that item leaked from full queue.
+	DLQReasonLeak DLQReason = iota
+	// DLQReasonDeadline means that item dropped due to deadline (see Config.DeadlineToDLQ).
+	DLQReasonDeadline
+	// DLQReasonFail means that item processing failed after all retries (see Config.FailToDLQ).
+	DLQReasonFail
+	// DLQReasonClose means that item remained in the queue on force close.
+	DLQReasonClose
+)
+
+func (r DLQReason) String() string {
+	switch r {
+	case DLQReasonLeak:
+		return "leak"
+	case DLQReasonDeadline:
+		return "deadline"
+	case DLQReasonFail:
+		return "fail"
+	case DLQReasonClose:
+		return "close"
+	}
+	return "unknown"
+}
+
+// DeadLetter is an envelope of item sent to DLQ.
+type DeadLetter struct {
+	// Item payload.
+	Payload any
+	// Reason of sending to DLQ.
+	Reason DLQReason
+	// Last processing error (if item was processed at least once).
+	Err error
+	// Number of retries.
+	Retries uint32
+	// Time of the original enqueue (retries don't reset it).
+	Enqueued time.Time
+	// Sub-queue name (for prioritized queues only).
+	Subq string
+	// Leak direction (for DLQReasonLeak only).
+	Direction LeakDirection
+}
+
+// DeadLetterEnqueuer describes DLQ that takes envelopes instead of raw payloads.
+// If Config.DLQ implements this interface, queue will use EnqueueDeadLetter instead of Enqueue method.
+// DLQ owns the envelope after call.
+type DeadLetterEnqueuer interface {
+	Enqueuer
+	// EnqueueDeadLetter puts envelope to DLQ.
+	EnqueueDeadLetter(dl *DeadLetter) error
+}
+
+// Default interval between DLQ enqueue attempts.
+const defaultDLQRetryInterval = 10 * time.Millisecond
+
+// Deliver item to DLQ considering fallback params (retries and secondary DLQ).
+// Param wait enables retries and must return true if waiting was interrupted, nil wait means no retries.
+// Returns error if item finally failed to put to DLQ, so caller must count it as lost.
+func (q *Queue) deliverDLQ(itm *item, reason DLQReason, dir LeakDirection, wait func(time.Duration) bool) error {
+	c := q.c()
+	err := q.toDLQ(c.DLQ, itm, reason, dir)
+	if err == nil {
+		return nil
+	}
+	if l := q.l(); l != nil {
+		l.Warn("DLQ enqueue failed", "reason", reason.String(), "error", err)
+	}
+	if c.DLQRetryTimeout > 0 && wait != nil {
+		// Blocking retry is available only for workers, leak and close never wait.
+		n := int(c.DLQRetryTimeout / c.DLQRetryInterval)
+		for i := 0; i < n && err != nil; i++ {
+			if wait(c.DLQRetryInterval) {
+				break
+			}
+			err = q.toDLQ(c.DLQ, itm, reason, dir)
+		}
+	}
+	if err != nil && c.DLQSecondary != nil {
+		err = q.toDLQ(c.DLQSecondary, itm, reason, dir)
+	}
+	if err == nil {
+		return nil
+	}
+	if l := q.l(); l != nil {
+		l.Error("item lost due to DLQ enqueue failure", "reason", reason.String(), "error", err)
+	}
+	if c.DLQErrorHandler != nil {
+		c.DLQErrorHandler(itm.payload, reason, err)
+	}
+	return err
+}
+
+// Send item to given DLQ considering envelope mode.
+func (q *Queue) toDLQ(dlq Enqueuer, itm *item, reason DLQReason, dir LeakDirection) error {
+	dle, ok := dlq.(DeadLetterEnqueuer)
+	if !ok {
+		return dlq.Enqueue(itm.payload)
+	}
+	dl := &DeadLetter{
+		Payload:   itm.payload,
+		Reason:    reason,
+		Err:       itm.err,
+		Retries:   itm.retries,
+		Direction: dir,
+	}
+	if itm.created > 0 {
+		dl.Enqueued = time.Unix(0, itm.created)
+	}
+	if qc := q.c().QoS; qc != nil && int(itm.subqi) < len(qc.Queues) {
+		dl.Subq = qc.Queues[itm.subqi].Name
+	}
+	return dle.EnqueueDeadLetter(dl)
+}
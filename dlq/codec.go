@@ -0,0 +1,88 @@
+package dlq
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/koykov/queue"
+)
+
+// Codec describes serialization of dead letters to lines.
+// Encoded line must not contain line breaks.
+type Codec interface {
+	// Encode appends encoded dead letter to dst.
+	Encode(dst []byte, dl *queue.DeadLetter) ([]byte, error)
+	// Decode decodes line to dead letter.
+	Decode(p []byte, dl *queue.DeadLetter) error
+}
+
+// JSONCodec encodes dead letters as JSON objects.
+// Please note, decoded payload contains generic JSON values (map[string]any, []any, string, float64, ...).
+type JSONCodec struct{}
+
+type jsonRecord struct {
+	Payload   any       `json:"payload"`
+	Reason    string    `json:"reason"`
+	Err       string    `json:"error,omitempty"`
+	Retries   uint32    `json:"retries,omitempty"`
+	Enqueued  time.Time `json:"enqueued"`
+	Subq      string    `json:"subq,omitempty"`
+	Direction string    `json:"direction,omitempty"`
+}
+
+func (JSONCodec) Encode(dst []byte, dl *queue.DeadLetter) ([]byte, error) {
+	rec := jsonRecord{
+		Payload:  dl.Payload,
+		Reason:   dl.Reason.String(),
+		Retries:  dl.Retries,
+		Enqueued: dl.Enqueued,
+		Subq:     dl.Subq,
+	}
+	if dl.Err != nil {
+		rec.Err = dl.Err.Error()
+	}
+	if dl.Reason == queue.DLQReasonLeak {
+		rec.Direction = dl.Direction.String()
+	}
+	p, err := json.Marshal(&rec)
+	if err != nil {
+		return dst, err
+	}
+	return append(dst, p...), nil
+}
+
+func (JSONCodec) Decode(p []byte, dl *queue.DeadLetter) error {
+	var rec jsonRecord
+	if err := json.Unmarshal(p, &rec); err != nil {
+		return err
+	}
+	reason, err := ParseReason(rec.Reason)
+	if err != nil {
+		return err
+	}
+	*dl = queue.DeadLetter{
+		Payload:  rec.Payload,
+		Reason:   reason,
+		Retries:  rec.Retries,
+		Enqueued: rec.Enqueued,
+		Subq:     rec.Subq,
+	}
+	if len(rec.Err) > 0 {
+		dl.Err = errors.New(rec.Err)
+	}
+	if rec.Direction == queue.LeakDirectionFront.String() {
+		dl.Direction = queue.LeakDirectionFront
+	}
+	return nil
+}
+
+// ParseReason converts string to DLQ reason.
+func ParseReason(s string) (queue.DLQReason, error) {
+	for r := queue.DLQReasonLeak; r <= queue.DLQReasonClose; r++ {
+		if r.String() == s {
+			return r, nil
+		}
+	}
+	return 0, ErrBadReason
+}
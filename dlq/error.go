@@ -0,0 +1,10 @@
+package dlq
+
+import "errors"
+
+var (
+	ErrBadReason = errors.New("unknown DLQ reason")
+	ErrNoFiles   = errors.New("no files provided")
+	ErrNoDir     = errors.New("no directory provided")
+	ErrClosed    = errors.New("DLQ closed")
+)
@@ -0,0 +1,265 @@
+package dlq
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/koykov/queue"
+)
+
+// SyncPolicy indicates when written dead letters flush to disk (fsync).
+type SyncPolicy uint8
+
+const (
+	// SyncNever leaves flushing to OS. Files sync only on rotation and close.
+	SyncNever SyncPolicy = iota
+	// SyncInterval flushes files periodically (see FileConfig.SyncInterval).
+	SyncInterval
+	// SyncAlways flushes file after each write. The most durable and the slowest policy.
+	SyncAlways
+)
+
+const (
+	defaultPrefix       = "dlq"
+	defaultSyncInterval = time.Second
+	defaultMaxSize      = 64 * 1024 * 1024
+
+	fileExt = ".dlq"
+	// File name timestamp layout. Lexicographical order of names matches chronological order.
+	fileTSLayout = "20060102T150405.000000000"
+)
+
+// FileConfig describes params of file-backed DLQ.
+type FileConfig struct {
+	// Directory to store files.
+	// Mandatory param.
+	Dir string
+	// Prefix of file names. Files names as <prefix>-<timestamp>.dlq.
+	// If this param omit defaultPrefix ("dlq") will use instead.
+	Prefix string
+	// Codec to encode dead letters.
+	// If this param omit JSONCodec will use instead.
+	Codec Codec
+	// Sync policy.
+	// If this param omit SyncNever will use.
+	Sync SyncPolicy
+	// Sync interval for SyncInterval policy.
+	// If this param omit defaultSyncInterval (1 second) will use instead.
+	SyncInterval time.Duration
+	// MaxSize of the file in bytes. File rotates when its size exceeds that limit.
+	// If this param omit defaultMaxSize (64 MB) will use instead.
+	MaxSize int64
+	// MaxAge of the file. File rotates on next write after that interval since creation.
+	// If this param omit files will rotate only by size.
+	MaxAge time.Duration
+	// Clock represents clock keeper.
+	// If this param omit native clock will use instead.
+	Clock queue.Clock
+}
+
+// File is a file-backed DLQ. It appends dead letters to rotating local files in line-delimited format.
+//
+// File implements queue.DeadLetterEnqueuer interface, so queue will send envelopes to it. Use Reader to inspect and
+// redrive written dead letters.
+type File struct {
+	conf FileConfig
+	mux  sync.Mutex
+	f    *os.File
+	// Actual file size, creation time and dirty (not synced) flag.
+	sz    int64
+	ctime time.Time
+	dirty bool
+	buf   []byte
+
+	done chan struct{}
+	once sync.Once
+	err  error
+}
+
+// NewFile makes new file-backed DLQ instance.
+func NewFile(config FileConfig) (*File, error) {
+	if len(config.Dir) == 0 {
+		return nil, ErrNoDir
+	}
+	if len(config.Prefix) == 0 {
+		config.Prefix = defaultPrefix
+	}
+	if config.Codec == nil {
+		config.Codec = JSONCodec{}
+	}
+	if config.SyncInterval == 0 {
+		config.SyncInterval = defaultSyncInterval
+	}
+	if config.MaxSize == 0 {
+		config.MaxSize = defaultMaxSize
+	}
+	if config.Clock == nil {
+		config.Clock = nativeClock{}
+	}
+	if err := os.MkdirAll(config.Dir, 0755); err != nil {
+		return nil, err
+	}
+	f := &File{conf: config, done: make(chan struct{})}
+	if config.Sync == SyncInterval {
+		go f.syncLoop()
+	}
+	return f, nil
+}
+
+// Enqueue puts x to the file. Raw payload wraps to dead letter with zero fields (reason leak).
+func (f *File) Enqueue(x any) error {
+	if dl, ok := x.(*queue.DeadLetter); ok {
+		return f.EnqueueDeadLetter(dl)
+	}
+	return f.EnqueueDeadLetter(&queue.DeadLetter{Payload: x})
+}
+
+// EnqueueDeadLetter puts dead letter to the file.
+func (f *File) EnqueueDeadLetter(dl *queue.DeadLetter) (err error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	if f.err != nil {
+		return f.err
+	}
+	if f.buf, err = f.conf.Codec.Encode(f.buf[:0], dl); err != nil {
+		return
+	}
+	f.buf = append(f.buf, '\n')
+	if err = f.rotate(int64(len(f.buf))); err != nil {
+		return
+	}
+	var n int
+	n, err = f.f.Write(f.buf)
+	f.sz += int64(n)
+	f.dirty = true
+	if err != nil {
+		return
+	}
+	if f.conf.Sync == SyncAlways {
+		err = f.sync()
+	}
+	return
+}
+
+// Sync flushes actual file to disk.
+func (f *File) Sync() error {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	return f.sync()
+}
+
+// Files returns sorted list of files of the DLQ (including actual file).
+func (f *File) Files() ([]string, error) {
+	return ListFiles(f.conf.Dir, f.conf.Prefix)
+}
+
+// Close syncs and closes actual file. DLQ must not be used after that call.
+func (f *File) Close() (err error) {
+	f.once.Do(func() {
+		close(f.done)
+		f.mux.Lock()
+		defer f.mux.Unlock()
+		err = f.closeFile()
+		f.err = ErrClosed
+	})
+	return
+}
+
+// Check rotation conditions and open new file if needed.
+func (f *File) rotate(n int64) error {
+	if f.f != nil {
+		full := f.sz > 0 && f.sz+n > f.conf.MaxSize
+		old := f.conf.MaxAge > 0 && f.conf.Clock.Now().Sub(f.ctime) >= f.conf.MaxAge
+		if !full && !old {
+			return nil
+		}
+		if err := f.closeFile(); err != nil {
+			return err
+		}
+	}
+	now := f.conf.Clock.Now()
+	fn := filepath.Join(f.conf.Dir, f.conf.Prefix+"-"+now.UTC().Format(fileTSLayout)+fileExt)
+	fh, err := os.OpenFile(fn, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	fi, err := fh.Stat()
+	if err != nil {
+		_ = fh.Close()
+		return err
+	}
+	f.f, f.sz, f.ctime = fh, fi.Size(), now
+	return nil
+}
+
+func (f *File) sync() error {
+	if f.f == nil || !f.dirty {
+		return nil
+	}
+	f.dirty = false
+	return f.f.Sync()
+}
+
+func (f *File) closeFile() error {
+	if f.f == nil {
+		return nil
+	}
+	err := f.sync()
+	if err1 := f.f.Close(); err == nil {
+		err = err1
+	}
+	f.f = nil
+	return err
+}
+
+func (f *File) syncLoop() {
+	t := time.NewTicker(f.conf.SyncInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			_ = f.Sync()
+		case <-f.done:
+			return
+		}
+	}
+}
+
+// Reader makes reader of all files of the DLQ.
+func (f *File) Reader() (*Reader, error) {
+	files, err := f.Files()
+	if err != nil {
+		return nil, err
+	}
+	return NewReader(f.conf.Codec, files...)
+}
+
+// ListFiles returns sorted list of DLQ files with given prefix in dir. Files with the same prefix sort chronologically.
+// Empty prefix means any prefix.
+func ListFiles(dir, prefix string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var r []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, fileExt) {
+			continue
+		}
+		if len(prefix) > 0 && !strings.HasPrefix(name, prefix+"-") {
+			continue
+		}
+		r = append(r, filepath.Join(dir, name))
+	}
+	sort.Strings(r)
+	return r, nil
+}
+
+type nativeClock struct{}
+
+func (nativeClock) Now() time.Time { return time.Now() }
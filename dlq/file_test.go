@@ -0,0 +1,99 @@
+package dlq
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/koykov/queue"
+)
+
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time { return c.now }
+
+func TestFile(t *testing.T) {
+	t.Run("rotate", func(t *testing.T) {
+		clk := &testClock{now: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+		f, err := NewFile(FileConfig{Dir: t.TempDir(), MaxSize: 200, MaxAge: time.Minute, Sync: SyncAlways, Clock: clk})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Two records per file due to size limit.
+		for i := 0; i < 4; i++ {
+			clk.now = clk.now.Add(time.Millisecond)
+			if err = f.Enqueue(i); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// Rotation by age.
+		clk.now = clk.now.Add(time.Minute)
+		_ = f.EnqueueDeadLetter(&queue.DeadLetter{Payload: 4, Reason: queue.DLQReasonFail})
+		_ = f.Close()
+		if err = f.Enqueue(5); !errors.Is(err, ErrClosed) {
+			t.Errorf("closed error mismatch: %v", err)
+		}
+
+		files, _ := f.Files()
+		if len(files) != 3 {
+			t.Fatalf("files count mismatch: need 3, got %d", len(files))
+		}
+		r, err := f.Reader()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var n float64
+		for {
+			dl, err := r.Next()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if dl == nil {
+				break
+			}
+			if dl.Payload != n {
+				t.Errorf("payload mismatch: need %v, got %v", n, dl.Payload)
+			}
+			n++
+		}
+		if n != 5 {
+			t.Errorf("read mismatch: need 5, got %v", n)
+		}
+	})
+	t.Run("queue", func(t *testing.T) {
+		f, err := NewFile(FileConfig{Dir: t.TempDir(), Sync: SyncInterval, SyncInterval: time.Millisecond})
+		if err != nil {
+			t.Fatal(err)
+		}
+		q, err := queue.New(&queue.Config{
+			Capacity:  10,
+			Workers:   1,
+			Worker:    failWorker{},
+			DLQ:       f,
+			FailToDLQ: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = q.Enqueue("foo")
+		var dl *queue.DeadLetter
+		for i := 0; i < 1000 && dl == nil; i++ {
+			time.Sleep(time.Millisecond)
+			if r, err := f.Reader(); err == nil {
+				dl, _ = r.Next()
+				_ = r.Close()
+			}
+		}
+		_ = q.Close()
+		_ = f.Close()
+		if dl == nil || dl.Payload != "foo" || dl.Reason != queue.DLQReasonFail || dl.Err == nil {
+			t.Errorf("dead letter mismatch: %+v", dl)
+		}
+	})
+}
+
+type failWorker struct{}
+
+func (failWorker) Do(_ any) error { return errors.New("fail") }
@@ -0,0 +1,103 @@
+package dlq
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	"github.com/koykov/queue"
+)
+
+// Max line size of the reader.
+const maxLineSize = 64 * 1024 * 1024
+
+// Reader reads dead letters from files consecutively.
+//
+// Reader implements queue.Dequeuer interface, so it may be used as a source of queue.Redriver. Reading isn't destructive,
+// files stay as is.
+type Reader struct {
+	files []string
+	codec Codec
+
+	idx  int
+	line int
+	f    *os.File
+	s    *bufio.Scanner
+	err  error
+}
+
+// NewReader makes new reader of given files. If codec is nil JSONCodec will use instead.
+func NewReader(codec Codec, files ...string) (*Reader, error) {
+	if len(files) == 0 {
+		return nil, ErrNoFiles
+	}
+	if codec == nil {
+		codec = JSONCodec{}
+	}
+	return &Reader{files: files, codec: codec}, nil
+}
+
+// Dequeue reads next dead letter (*queue.DeadLetter).
+// Returns false if all files are read or error occurred, see Err.
+func (r *Reader) Dequeue() (any, bool) {
+	dl, err := r.Next()
+	if err != nil {
+		r.err = err
+		return nil, false
+	}
+	return dl, dl != nil
+}
+
+// Next reads next dead letter. Returns nil dead letter and nil error if all files are read.
+func (r *Reader) Next() (*queue.DeadLetter, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	for {
+		if r.s == nil {
+			if r.idx >= len(r.files) {
+				return nil, nil
+			}
+			f, err := os.Open(r.files[r.idx])
+			if err != nil {
+				return nil, err
+			}
+			r.f, r.s, r.line = f, bufio.NewScanner(f), 0
+			r.s.Buffer(nil, maxLineSize)
+		}
+		if r.s.Scan() {
+			r.line++
+			p := r.s.Bytes()
+			if len(p) == 0 {
+				continue
+			}
+			var dl queue.DeadLetter
+			if err := r.codec.Decode(p, &dl); err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", r.files[r.idx], r.line, err)
+			}
+			return &dl, nil
+		}
+		err := r.s.Err()
+		_ = r.f.Close()
+		r.f, r.s = nil, nil
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", r.files[r.idx], err)
+		}
+		r.idx++
+	}
+}
+
+// Err returns the first error occurred during reading.
+func (r *Reader) Err() error {
+	return r.err
+}
+
+// Close closes current file.
+func (r *Reader) Close() error {
+	if r.f != nil {
+		err := r.f.Close()
+		r.f, r.s = nil, nil
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package dlq
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/koykov/queue"
+)
+
+func TestReader(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	letters := []queue.DeadLetter{
+		{Payload: "foo", Reason: queue.DLQReasonFail, Err: errors.New("fail"), Retries: 3, Enqueued: ts},
+		{Payload: map[string]any{"id": 1.}, Reason: queue.DLQReasonLeak, Direction: queue.LeakDirectionFront, Subq: "low"},
+		{Payload: 2., Reason: queue.DLQReasonClose},
+	}
+	dir := t.TempDir()
+	var files []string
+	for i, lines := range [][]queue.DeadLetter{letters[:2], letters[2:]} {
+		var buf []byte
+		for j := range lines {
+			var err error
+			if buf, err = (JSONCodec{}).Encode(buf, &lines[j]); err != nil {
+				t.Fatal(err)
+			}
+			buf = append(buf, '\n')
+		}
+		fn := filepath.Join(dir, string(rune('a'+i))+".dlq")
+		if err := os.WriteFile(fn, buf, 0644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, fn)
+	}
+
+	r, err := NewReader(nil, files...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = r.Close() }()
+	var i int
+	for {
+		x, ok := r.Dequeue()
+		if !ok {
+			break
+		}
+		dl, exp := x.(*queue.DeadLetter), letters[i]
+		if dl.Reason != exp.Reason || dl.Retries != exp.Retries || !dl.Enqueued.Equal(exp.Enqueued) ||
+			dl.Subq != exp.Subq || dl.Direction != exp.Direction || (exp.Err != nil && dl.Err.Error() != exp.Err.Error()) {
+			t.Errorf("dead letter #%d mismatch: %+v", i, dl)
+		}
+		i++
+	}
+	if r.Err() != nil || i != len(letters) {
+		t.Errorf("read mismatch: %d items, error %v", i, r.Err())
+	}
+}
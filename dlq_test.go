@@ -0,0 +1,225 @@
+package queue
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/koykov/queue/qos"
+)
+
+type envelopeDLQ struct {
+	DummyDLQ
+	ch chan *DeadLetter
+}
+
+func (q envelopeDLQ) EnqueueDeadLetter(dl *DeadLetter) error {
+	q.ch <- dl
+	return nil
+}
+
+// DLQ that rejects first n items.
+type rejectDLQ struct {
+	DummyDLQ
+	n, c int32
+}
+
+func (q *rejectDLQ) Enqueue(_ any) error {
+	if atomic.AddInt32(&q.c, 1) <= q.n {
+		return errors.New("rejected")
+	}
+	return nil
+}
+
+func awaitDeadLetter(t *testing.T, dlq envelopeDLQ) *DeadLetter {
+	select {
+	case dl := <-dlq.ch:
+		return dl
+	case <-time.After(time.Second):
+		t.Fatal("dead letter not found")
+	}
+	return nil
+}
+
+// Fails each item and moves the clock forward.
+type tickFailWorker struct {
+	clk *testClock
+}
+
+func (w tickFailWorker) Do(_ any) error {
+	w.clk.Add(time.Second)
+	return errors.New("fail")
+}
+
+func TestDeadLetter(t *testing.T) {
+	t.Run("fail", func(t *testing.T) {
+		dlq := envelopeDLQ{ch: make(chan *DeadLetter, 64)}
+		now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+		clk := newTestClock(now)
+		q, err := New(&Config{
+			Capacity:      10,
+			Workers:       1,
+			Worker:        tickFailWorker{clk: clk},
+			MaxRetries:    2,
+			RetryInterval: time.Millisecond,
+			DLQ:           dlq,
+			FailToDLQ:     true,
+			Clock:         clk,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = q.Enqueue("foo")
+		dl := awaitDeadLetter(t, dlq)
+		if dl.Payload != "foo" || dl.Reason != DLQReasonFail || dl.Retries != 2 || dl.Err == nil || !dl.Enqueued.Equal(now) {
+			t.Errorf("dead letter mismatch: %+v", dl)
+		}
+		_ = q.Close()
+	})
+	t.Run("leak", func(t *testing.T) {
+		dlq := envelopeDLQ{ch: make(chan *DeadLetter, 64)}
+		w := &blockWorker{in: make(chan struct{}), out: make(chan struct{})}
+		q, err := New(&Config{
+			QoS: qos.New(qos.PQ, qos.DummyPriorityEvaluator{}).
+				SetEgressCapacity(1).
+				AddQueue(qos.Queue{Name: "high", Capacity: 1, Weight: 1}).
+				AddQueue(qos.Queue{Name: "low", Capacity: 1, Weight: 1}),
+			Workers:       1,
+			Worker:        w,
+			DLQ:           dlq,
+			LeakDirection: LeakDirectionFront,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = q.Enqueue(0)
+		<-w.in
+		for i := 1; i <= 5; i++ {
+			_ = q.Enqueue(i)
+		}
+		dl := awaitDeadLetter(t, dlq)
+		if dl.Reason != DLQReasonLeak || dl.Direction != LeakDirectionFront || (dl.Subq != "high" && dl.Subq != "low") {
+			t.Errorf("dead letter mismatch: %+v", dl)
+		}
+		close(w.out)
+		go func() {
+			for range w.in {
+			}
+		}()
+		_ = q.Close()
+	})
+	t.Run("fallback", func(t *testing.T) {
+		type lost struct {
+			x      any
+			reason DLQReason
+		}
+		run := func(t *testing.T, conf *Config) *StatsWriter {
+			sw := NewStatsWriter()
+			conf.Capacity, conf.Workers, conf.Worker, conf.FailToDLQ, conf.MetricsWriter = 10, 1, failWorker{}, true, sw
+			q, err := New(conf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = q.Enqueue("foo")
+			_ = q.Close()
+			for i := 0; i < 1000 && sw.Stats().Exec.Count == 0; i++ {
+				time.Sleep(time.Millisecond)
+			}
+			time.Sleep(time.Millisecond * 10)
+			return sw
+		}
+		t.Run("retry", func(t *testing.T) {
+			dlq := &rejectDLQ{n: 2}
+			sw := run(t, &Config{DLQ: dlq, DLQRetryTimeout: time.Second, DLQRetryInterval: time.Millisecond})
+			if s := sw.Stats(); s.Lost != 0 || atomic.LoadInt32(&dlq.c) != 3 {
+				t.Errorf("retry mismatch: lost %d, attempts %d", s.Lost, dlq.c)
+			}
+		})
+		t.Run("secondary", func(t *testing.T) {
+			sdlq := envelopeDLQ{ch: make(chan *DeadLetter, 64)}
+			sw := run(t, &Config{DLQ: &rejectDLQ{n: 1}, DLQSecondary: sdlq})
+			dl := awaitDeadLetter(t, sdlq)
+			if dl.Payload != "foo" || dl.Reason != DLQReasonFail || sw.Stats().Lost != 0 {
+				t.Errorf("secondary mismatch: %+v", dl)
+			}
+		})
+		t.Run("front", func(t *testing.T) {
+			sw := NewStatsWriter()
+			w := &blockWorker{in: make(chan struct{}), out: make(chan struct{})}
+			q, err := New(&Config{
+				Capacity:      1,
+				Workers:       1,
+				Worker:        w,
+				DLQ:           &rejectDLQ{n: 100},
+				LeakDirection: LeakDirectionFront,
+				MetricsWriter: sw,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = q.Enqueue(0)
+			<-w.in
+			_ = q.Enqueue(1)
+			// Front item lost, but incoming one must take its place.
+			if err = q.Enqueue(2); err != nil || q.Size() != 1 || sw.Stats().Lost != 1 {
+				t.Errorf("front leak mismatch: err %v, size %d, lost %d", err, q.Size(), sw.Stats().Lost)
+			}
+			close(w.out)
+			go func() {
+				for range w.in {
+				}
+			}()
+			_ = q.Close()
+		})
+		t.Run("force", func(t *testing.T) {
+			sw := NewStatsWriter()
+			w := &blockWorker{in: make(chan struct{}), out: make(chan struct{})}
+			q, err := New(&Config{
+				Capacity:         10,
+				Workers:          1,
+				Worker:           w,
+				DLQ:              &rejectDLQ{n: 1 << 20},
+				DLQRetryTimeout:  time.Second,
+				DLQRetryInterval: time.Millisecond,
+				MetricsWriter:    sw,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = q.Enqueue(0)
+			<-w.in
+			for i := 1; i <= 5; i++ {
+				_ = q.Enqueue(i)
+			}
+			// Dropped items must not wait for DLQ retries.
+			start := time.Now()
+			_ = q.ForceClose()
+			if d := time.Since(start); d > time.Millisecond*500 || sw.Stats().Lost != 5 {
+				t.Errorf("force close mismatch: took %s, lost %d", d, sw.Stats().Lost)
+			}
+			close(w.out)
+		})
+		t.Run("lost", func(t *testing.T) {
+			ch := make(chan lost, 1)
+			sw := run(t, &Config{
+				DLQ:              &rejectDLQ{n: 100},
+				DLQRetryTimeout:  time.Millisecond * 5,
+				DLQRetryInterval: time.Millisecond,
+				DLQSecondary:     &rejectDLQ{n: 100},
+				DLQErrorHandler:  func(x any, reason DLQReason, _ error) { ch <- lost{x, reason} },
+			})
+			select {
+			case l := <-ch:
+				if l.x != "foo" || l.reason != DLQReasonFail {
+					t.Errorf("lost item mismatch: %+v", l)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("error handler wasn't called")
+			}
+			if s := sw.Stats(); s.Lost != 1 || s.Fail != 1 || s.Size != 0 || s.Leak["front"] != 0 {
+				t.Errorf("metrics mismatch: lost %d, fail %d, size %d, leak %v", s.Lost, s.Fail, s.Size, s.Leak)
+			}
+		})
+	})
+}
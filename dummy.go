@@ -18,10 +18,17 @@ func (DummyMetrics) QueueRetry(_ time.Duration)            {}
 func (DummyMetrics) QueueLeak(_ string)                    {}
 func (DummyMetrics) QueueDeadline()                        {}
 func (DummyMetrics) QueueLost()                            {}
+func (DummyMetrics) QueueFail(_ bool)                      {}
 func (DummyMetrics) QueueExec(_ time.Duration)             {}
+func (DummyMetrics) QueueWait(_ time.Duration)             {}
+func (DummyMetrics) QueueSchedule(_ int)                   {}
+func (DummyMetrics) QueueBreaker(_ string)                 {}
+func (DummyMetrics) OverflowPut()                          {}
+func (DummyMetrics) OverflowPull()                         {}
 func (DummyMetrics) SubqPut(_ string)                      {}
 func (DummyMetrics) SubqPull(_ string)                     {}
 func (DummyMetrics) SubqLeak(_ string)                     {}
+func (DummyMetrics) SubqWait(_ string, _ time.Duration)    {}
 
 // DummyDLQ is a stub DLQ implementation. It does nothing and need for queues with leak tolerance.
 // It just leaks data to the trash.
@@ -9,6 +9,22 @@ var (
 	ErrNoWorkers   = errors.New("no workers available")
 	ErrNoQueue     = errors.New("no queue provided")
 	ErrQueueClosed = errors.New("queue closed")
+	ErrBreakerOpen = errors.New("circuit breaker is open")
+	ErrNoSource    = errors.New("no source provided")
+	ErrNoTarget    = errors.New("no target provided")
+
+	ErrMinGtMax         = errors.New("min workers greater than max, min will be reduced to max")
+	ErrFactorNegative   = errors.New("negative factor, default value will use instead")
+	ErrFactorLimit      = errors.New("factor exceeds limit, it will be reduced to limit")
+	ErrWakeupLtSleep    = errors.New("wakeup factor less than sleep factor, it will be raised to sleep factor")
+	ErrNoRetryInterval  = errors.New("param works only with non-empty RetryInterval")
+	ErrNoDLQ            = errors.New("param works only with non-empty DLQ")
+	ErrBadLeakDirection = errors.New("unknown leak direction")
+	ErrDeadlineLeDelay  = errors.New("deadline interval must be greater than delay interval")
+	ErrNoRateLimit      = errors.New("param works only with non-empty RateLimit")
+	ErrBadFailureRate   = errors.New("failure rate outside range 0..1")
+	ErrBreakerNeverOpen = errors.New("breaker will never open due to no failure threshold and rate")
+	ErrUnknownSubq      = errors.New("unknown QoS sub-queue")
 
 	ErrSchedMinGtMax = errors.New("min workers greater than max")
 	ErrSchedZeroMax  = errors.New("max workers must be greater than 0")
@@ -18,4 +34,7 @@ var (
 	ErrSchedBadMin   = errors.New("minute outside range 0..59")
 	ErrSchedBadSec   = errors.New("second outside range 0..59")
 	ErrSchedBadMsec  = errors.New("millisecond outside range 0..999")
+	ErrSchedBadDays  = errors.New("bad weekdays provided")
+	ErrSchedBadDate  = errors.New("bad date provided")
+	ErrSchedOverlap  = errors.New("schedule range overlaps existing range")
 )
@@ -28,10 +28,21 @@ func (e *fifo) dequeue() (item, bool) {
 	return itm, ok
 }
 
-func (e *fifo) dequeueSQ(_ uint32) (item, bool) {
+func (e *fifo) classify(_ *item) {}
+
+func (e *fifo) evict(_ uint32) (item, bool) {
 	return e.dequeue()
 }
 
+func (e *fifo) tryDequeue() (item, bool) {
+	select {
+	case itm, ok := <-e.c:
+		return itm, ok
+	default:
+		return item{}, false
+	}
+}
+
 func (e *fifo) size() int {
 	return len(e.c)
 }
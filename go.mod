@@ -1,5 +1,8 @@
 module github.com/koykov/queue
 
-go 1.18
+go 1.21
 
-require github.com/koykov/bitset v1.0.0
+require (
+	github.com/koykov/bitset v1.0.0
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -1,5 +1,7 @@
 package queue
 
+import "context"
+
 // Enqueuer describes component that can enqueue items.
 type Enqueuer interface {
 	// Enqueue puts item to the queue.
@@ -19,12 +21,34 @@ type Interface interface {
 	Close() error
 }
 
+// Dequeuer describes component that can take items.
+type Dequeuer interface {
+	// Dequeue takes item in non-blocking mode.
+	// Returns false if there is no items to take.
+	Dequeue() (any, bool)
+}
+
+// OverflowQueue describes secondary queue to spill items from the full queue (see Config.Overflow).
+// Dequeue must return items in the order they were enqueued.
+type OverflowQueue interface {
+	Enqueuer
+	Dequeuer
+}
+
 // Worker describes queue worker interface.
 type Worker interface {
 	// Do process the item.
 	Do(x any) error
 }
 
+// ContextWorker describes worker that takes context of the item.
+// Context contains trace info of processing span if Config.Tracer set.
+type ContextWorker interface {
+	Worker
+	// DoContext process the item using given context.
+	DoContext(ctx context.Context, x any) error
+}
+
 // Internal engine definition.
 type engine interface {
 	// Init engine using config.
@@ -37,8 +61,14 @@ type engine interface {
 	// Returns true/false for non-blocking mode.
 	// Always returns true in blocking mode.
 	dequeue() (item, bool)
-	// Get item from sub-queue by given index.
-	dequeueSQ(subqi uint32) (item, bool)
+	// Evaluate sub-queue index of the item (engines with sub-queues only).
+	classify(itm *item)
+	// Get item to leak for incoming item of sub-queue by given index (considering eviction policy).
+	// Returns false if there is no item to leak.
+	evict(subqi uint32) (item, bool)
+	// Get item from the engine in non-blocking mode.
+	// Returns false if engine is empty.
+	tryDequeue() (item, bool)
 	// Return count of collected items.
 	size() int
 	// Returns the whole capacity.
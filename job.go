@@ -12,4 +12,9 @@ type Job struct {
 	DelayInterval time.Duration
 	// DeadlineInterval limits maximum reasonable time to process job.
 	DeadlineInterval time.Duration
+	// Headers contains job metadata.
+	// If Config.Tracer set, trace context of the producer will inject to a copy of headers on enqueue (so set non-nil
+	// map to catch it), workers receive a copy of the job with these headers. Vice versa, if producer's context isn't
+	// available, trace context will extract from headers.
+	Headers map[string]string
 }
@@ -1,5 +1,13 @@
 package queue
 
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+)
+
 // Logger is an interface of logger interface.
 // Prints verbose messages.
 type Logger interface {
@@ -7,3 +15,156 @@ type Logger interface {
 	Print(v ...any)
 	Println(v ...any)
 }
+
+// StructuredLogger is an interface of leveled logger with key-value fields (args), like log/slog does.
+// *slog.Logger implements this interface, so it may be used directly.
+type StructuredLogger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+// NewHandlerLogger makes structured logger over given slog.Handler.
+func NewHandlerLogger(h slog.Handler) StructuredLogger {
+	return slog.New(h)
+}
+
+// NewLoggerHandler makes slog.Handler that writes records with level not less than given to legacy Logger.
+// Records format as `LEVEL message key=value ...` lines.
+func NewLoggerHandler(l Logger, level slog.Leveler) slog.Handler {
+	if level == nil {
+		level = slog.LevelDebug
+	}
+	return &loggerHandler{l: l, level: level}
+}
+
+// Fields of queue's log records.
+const (
+	logQueue   = "queue"
+	logWorker  = "worker"
+	logSchedID = "sched_id"
+	logRate    = "rate"
+	logSubq    = "subq"
+)
+
+// Make structured logger of the queue considering config params.
+// StructuredLogger takes precedence over legacy Logger. Returns nil if no logger specified.
+func newLogger(c *Config) StructuredLogger {
+	var l StructuredLogger
+	switch {
+	case c.StructuredLogger != nil:
+		l = c.StructuredLogger
+	case c.Logger != nil:
+		l = slog.New(NewLoggerHandler(c.Logger, slog.LevelDebug))
+	default:
+		return nil
+	}
+	if len(c.Name) > 0 {
+		l = fieldLogger{l: l, args: []any{logQueue, c.Name}}
+	}
+	return l
+}
+
+// fieldLogger adds permanent fields to every record.
+type fieldLogger struct {
+	l    StructuredLogger
+	args []any
+}
+
+func (f fieldLogger) Debug(msg string, args ...any) { f.l.Debug(msg, f.with(args)...) }
+func (f fieldLogger) Info(msg string, args ...any)  { f.l.Info(msg, f.with(args)...) }
+func (f fieldLogger) Warn(msg string, args ...any)  { f.l.Warn(msg, f.with(args)...) }
+func (f fieldLogger) Error(msg string, args ...any) { f.l.Error(msg, f.with(args)...) }
+
+func (f fieldLogger) with(args []any) []any {
+	r := make([]any, 0, len(f.args)+len(args))
+	r = append(r, f.args...)
+	return append(r, args...)
+}
+
+// loggerHandler is a slog.Handler implementation over legacy Logger.
+type loggerHandler struct {
+	l     Logger
+	level slog.Leveler
+	group string
+	attrs []slog.Attr
+}
+
+var lhPool = sync.Pool{New: func() any { return &strings.Builder{} }}
+
+func (h *loggerHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *loggerHandler) Handle(_ context.Context, r slog.Record) error {
+	buf := lhPool.Get().(*strings.Builder)
+	defer func() {
+		buf.Reset()
+		lhPool.Put(buf)
+	}()
+	_, _ = buf.WriteString(r.Level.String())
+	_ = buf.WriteByte(' ')
+	_, _ = buf.WriteString(r.Message)
+	for i := 0; i < len(h.attrs); i++ {
+		h.writeAttr(buf, "", h.attrs[i])
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		h.writeAttr(buf, h.group, a)
+		return true
+	})
+	h.l.Print(buf.String())
+	return nil
+}
+
+func (h *loggerHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	cpy := *h
+	cpy.attrs = make([]slog.Attr, 0, len(h.attrs)+len(attrs))
+	cpy.attrs = append(cpy.attrs, h.attrs...)
+	for i := 0; i < len(attrs); i++ {
+		a := attrs[i]
+		if len(h.group) > 0 {
+			a.Key = h.group + "." + a.Key
+		}
+		cpy.attrs = append(cpy.attrs, a)
+	}
+	return &cpy
+}
+
+func (h *loggerHandler) WithGroup(name string) slog.Handler {
+	if len(name) == 0 {
+		return h
+	}
+	cpy := *h
+	if len(cpy.group) > 0 {
+		cpy.group += "." + name
+	} else {
+		cpy.group = name
+	}
+	return &cpy
+}
+
+func (h *loggerHandler) writeAttr(buf *strings.Builder, group string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	key := a.Key
+	if len(group) > 0 {
+		key = group + "." + key
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		for _, ga := range a.Value.Group() {
+			h.writeAttr(buf, key, ga)
+		}
+		return
+	}
+	_ = buf.WriteByte(' ')
+	_, _ = buf.WriteString(key)
+	_ = buf.WriteByte('=')
+	s := a.Value.String()
+	if strings.ContainsAny(s, " \t\n\"=") {
+		s = strconv.Quote(s)
+	}
+	_, _ = buf.WriteString(s)
+}
@@ -0,0 +1,91 @@
+package queue
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type bufLogger struct {
+	mux sync.Mutex
+	buf []string
+}
+
+func (l *bufLogger) Printf(format string, v ...any) { l.Print(fmt.Sprintf(format, v...)) }
+func (l *bufLogger) Println(v ...any)               { l.Print(fmt.Sprint(v...)) }
+func (l *bufLogger) Print(v ...any) {
+	l.mux.Lock()
+	l.buf = append(l.buf, fmt.Sprint(v...))
+	l.mux.Unlock()
+}
+
+func (l *bufLogger) lines() []string {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	return append([]string(nil), l.buf...)
+}
+
+func TestLogger(t *testing.T) {
+	t.Run("handler", func(t *testing.T) {
+		bl := &bufLogger{}
+		l := slog.New(NewLoggerHandler(bl, slog.LevelInfo))
+		l.Debug("skip")
+		l.With("queue", "test").WithGroup("w").Info("worker init", "idx", 1, "msg", "a b")
+		lines := bl.lines()
+		exp := `INFO worker init queue=test w.idx=1 w.msg="a b"`
+		if len(lines) != 1 || lines[0] != exp {
+			t.Errorf("output mismatch: need %q, got %q", exp, lines)
+		}
+	})
+	t.Run("legacy", func(t *testing.T) {
+		bl := &bufLogger{}
+		q, err := New(&Config{
+			Name:     "legacy",
+			Capacity: 10,
+			Workers:  2,
+			Worker:   nopWorker{},
+			Logger:   bl,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = q.Close()
+		var ok bool
+		for _, line := range bl.lines() {
+			if !strings.Contains(line, "queue=legacy") {
+				t.Errorf("queue field missing: %s", line)
+			}
+			if strings.HasPrefix(line, "DEBUG worker init") && strings.Contains(line, "worker=0") {
+				ok = true
+			}
+		}
+		if !ok {
+			t.Error("worker init record not found")
+		}
+	})
+	t.Run("structured", func(t *testing.T) {
+		bl := &bufLogger{}
+		q, err := New(&Config{
+			Capacity:         10,
+			Workers:          1,
+			Worker:           nopWorker{},
+			Logger:           &bufLogger{},
+			StructuredLogger: slog.New(NewLoggerHandler(bl, slog.LevelInfo)),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = q.Close()
+		lines := bl.lines()
+		if len(lines) == 0 || !strings.HasPrefix(lines[len(lines)-1], "INFO caught close signal") {
+			t.Errorf("close record not found: %q", lines)
+		}
+		for _, line := range lines {
+			if strings.HasPrefix(line, "DEBUG") {
+				t.Errorf("unexpected debug record: %s", line)
+			}
+		}
+	})
+}
@@ -16,7 +16,7 @@ type MetricsWriter interface {
 	WorkerWakeup(idx uint32)
 	// WorkerWait registers how many worker waits due to delayed execution.
 	WorkerWait(idx uint32, dur time.Duration)
-	// WorkerStop registers when sleeping worker stops.
+	// WorkerStop registers when active or sleeping worker stops.
 	WorkerStop(idx uint32, force bool, status string)
 	// QueuePut registers income of new item to the queue.
 	QueuePut()
@@ -31,8 +31,24 @@ type MetricsWriter interface {
 	QueueDeadline()
 	// QueueLost registers lost items missed queue and DLQ.
 	QueueLost()
+	// QueueFail registers item's failure after all processing attempts.
+	// Param lost indicates that DLQ rejected the item (see Config.FailToDLQ). Unlike QueueLost it doesn't change queue
+	// size, since the item is already pulled from the queue.
+	QueueFail(lost bool)
 	// QueueExec registers how long queue executes a job.
 	QueueExec(spent time.Duration)
+	// QueueWait registers how long item waits in the queue (since enqueue or retry) before worker takes it.
+	QueueWait(dur time.Duration)
+	// QueueSchedule registers switch of schedule rule.
+	// Param schedID contains -1 if no rule hits.
+	QueueSchedule(schedID int)
+	// QueueBreaker registers circuit breaker state change.
+	// Param state may be "closed", "open" or "half-open".
+	QueueBreaker(state string)
+	// OverflowPut registers item's spill from the full queue to the overflow queue.
+	OverflowPut()
+	// OverflowPull registers item's return from the overflow queue to the queue.
+	OverflowPull()
 
 	// SubqPut registers income of new item to the sub-queue.
 	SubqPut(subq string)
@@ -40,4 +56,11 @@ type MetricsWriter interface {
 	SubqPull(subq string)
 	// SubqLeak registers item's drop from the full queue.
 	SubqLeak(subq string)
+	// SubqWait registers how long item waits in the sub-queue before forwarding to egress.
+	SubqWait(subq string, dur time.Duration)
+}
+
+// Optional interface of MetricsWriter that removes queue's metrics. Queue calls it once all workers stopped after close.
+type metricsUnregisterer interface {
+	Unregister()
 }
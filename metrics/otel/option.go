@@ -0,0 +1,23 @@
+package otel
+
+import (
+	"time"
+
+	"go.opentelemetry.io/otel/metric"
+)
+
+type Option func(writer *writer)
+
+func WithPrecision(precision time.Duration) Option {
+	return func(writer *writer) {
+		writer.prec = precision
+	}
+}
+
+// WithMeterProvider sets meter provider to create instruments.
+// If this option omit global meter provider will use instead.
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(writer *writer) {
+		writer.mp = provider
+	}
+}
@@ -0,0 +1,283 @@
+package otel
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+type Writer interface {
+	WorkerSetup(active, sleep, stop uint)
+	WorkerInit(idx uint32)
+	WorkerSleep(idx uint32)
+	WorkerWakeup(idx uint32)
+	WorkerWait(idx uint32, dur time.Duration)
+	WorkerStop(idx uint32, force bool, status string)
+	QueuePut()
+	QueuePull()
+	QueueRetry(delay time.Duration)
+	QueueLeak(direction string)
+	QueueDeadline()
+	QueueLost()
+	QueueFail(lost bool)
+	QueueExec(spent time.Duration)
+	QueueWait(dur time.Duration)
+	QueueSchedule(schedID int)
+	QueueBreaker(state string)
+	OverflowPut()
+	OverflowPull()
+	SubqPut(subq string)
+	SubqPull(subq string)
+	SubqLeak(subq string)
+	SubqWait(subq string, dur time.Duration)
+}
+
+const scope = "github.com/koykov/queue/metrics/otel"
+
+// writer is an OpenTelemetry implementation of queue.MetricsWriter.
+type writer struct {
+	name string
+	prec time.Duration
+	mp   metric.MeterProvider
+
+	// Actual workers numbers. UpDownCounter can't be set directly, so WorkerSetup applies difference with them.
+	active, sleep, idle int64
+
+	workersIdle, workersActive, workersSleep, queueSize, subqSize, overflowSize metric.Int64UpDownCounter
+	queueIn, queueOut, queueRetry, queueLeak, queueDeadline, queueLost, queueFail, queueBreaker, overflowIn, overflowOut,
+	subqIn, subqOut, subqLeak metric.Int64Counter
+	queueSchedule                                          metric.Int64Gauge
+	workerWait, retryDelay, queueExec, queueWait, subqWait metric.Float64Histogram
+
+	// Attributes cache.
+	attr  metric.MeasurementOption
+	attrs sync.Map
+}
+
+// NewWriter makes a new instance of metrics writer.
+func NewWriter(name string, options ...Option) (Writer, error) {
+	mw := &writer{name: name}
+	for _, fn := range options {
+		fn(mw)
+	}
+	if mw.prec == 0 {
+		mw.prec = time.Nanosecond
+	}
+	if mw.mp == nil {
+		mw.mp = otel.GetMeterProvider()
+	}
+	mw.attr = metric.WithAttributeSet(attribute.NewSet(attribute.String("queue", name)))
+
+	var err error
+	m := mw.mp.Meter(scope)
+	udc := func(dst *metric.Int64UpDownCounter, name, desc string) {
+		if err == nil {
+			*dst, err = m.Int64UpDownCounter(name, metric.WithDescription(desc))
+		}
+	}
+	cnt := func(dst *metric.Int64Counter, name, desc string) {
+		if err == nil {
+			*dst, err = m.Int64Counter(name, metric.WithDescription(desc))
+		}
+	}
+	hist := func(dst *metric.Float64Histogram, name, desc string) {
+		if err == nil {
+			*dst, err = m.Float64Histogram(name, metric.WithDescription(desc))
+		}
+	}
+	udc(&mw.workersIdle, "queue_workers_idle", "Indicates how many workers idle.")
+	udc(&mw.workersActive, "queue_workers_active", "Indicates how many workers active.")
+	udc(&mw.workersSleep, "queue_workers_sleep", "Indicates how many workers sleep.")
+	udc(&mw.queueSize, "queue_size", "Actual queue size.")
+	udc(&mw.subqSize, "queue_subq_size", "Actual sub-queue size.")
+	cnt(&mw.queueIn, "queue_in", "How many items comes to the queue.")
+	cnt(&mw.queueOut, "queue_out", "How many items leaves the queue.")
+	cnt(&mw.queueRetry, "queue_retry", "How many retries occurs.")
+	cnt(&mw.queueLeak, "queue_leak", "How many items dropped on the floor due to queue is full.")
+	cnt(&mw.queueDeadline, "queue_deadline", "How many items skipped due to deadline.")
+	cnt(&mw.queueLost, "queue_lost", "How many items throw to the trash due to force close.")
+	cnt(&mw.queueFail, "queue_fail", "How many items failed after all processing attempts.")
+	cnt(&mw.queueBreaker, "queue_breaker", "How many times circuit breaker switched to the state.")
+	udc(&mw.overflowSize, "queue_overflow_size", "Actual overflow queue size.")
+	cnt(&mw.overflowIn, "queue_overflow_in", "How many items spilled from the full queue to overflow queue.")
+	cnt(&mw.overflowOut, "queue_overflow_out", "How many items returned from overflow queue to the queue.")
+	cnt(&mw.subqIn, "queue_subq_in", "How many items comes to the sub-queue.")
+	cnt(&mw.subqOut, "queue_subq_out", "How many items leaves the sub-queue.")
+	cnt(&mw.subqLeak, "queue_subq_leak", "How many items dropped on the floor due to sub-queue is full.")
+	hist(&mw.workerWait, "queue_wait", "How many worker waits due to delayed execution.")
+	hist(&mw.retryDelay, "queue_retry_delay", "How many worker waits before retry.")
+	hist(&mw.queueExec, "queue_exec", "How many time the job executes.")
+	hist(&mw.queueWait, "queue_wait_time", "How long item waits in the queue before worker takes it.")
+	hist(&mw.subqWait, "queue_subq_wait_time", "How long item waits in the sub-queue before forwarding to egress.")
+	if err == nil {
+		mw.queueSchedule, err = m.Int64Gauge("queue_schedule", metric.WithDescription("Actual schedule rule ID."))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return mw, nil
+}
+
+func (w *writer) WorkerSetup(active, sleep, stop uint) {
+	w.setWorkers(w.workersActive, &w.active, int64(active))
+	w.setWorkers(w.workersSleep, &w.sleep, int64(sleep))
+	w.setWorkers(w.workersIdle, &w.idle, int64(stop))
+}
+
+func (w *writer) WorkerInit(_ uint32) {
+	w.addWorkers(w.workersActive, &w.active, 1)
+	w.addWorkers(w.workersIdle, &w.idle, -1)
+}
+
+func (w *writer) WorkerSleep(_ uint32) {
+	w.addWorkers(w.workersSleep, &w.sleep, 1)
+	w.addWorkers(w.workersActive, &w.active, -1)
+}
+
+func (w *writer) WorkerWakeup(_ uint32) {
+	w.addWorkers(w.workersActive, &w.active, 1)
+	w.addWorkers(w.workersSleep, &w.sleep, -1)
+}
+
+func (w *writer) WorkerWait(_ uint32, delay time.Duration) {
+	w.workerWait.Record(context.Background(), w.dur(delay), w.attr)
+}
+
+func (w *writer) WorkerStop(_ uint32, _ bool, status string) {
+	w.addWorkers(w.workersIdle, &w.idle, 1)
+	switch status {
+	case "active":
+		w.addWorkers(w.workersActive, &w.active, -1)
+	case "sleep":
+		w.addWorkers(w.workersSleep, &w.sleep, -1)
+	}
+}
+
+func (w *writer) QueuePut() {
+	ctx := context.Background()
+	w.queueIn.Add(ctx, 1, w.attr)
+	w.queueSize.Add(ctx, 1, w.attr)
+}
+
+func (w *writer) QueuePull() {
+	ctx := context.Background()
+	w.queueOut.Add(ctx, 1, w.attr)
+	w.queueSize.Add(ctx, -1, w.attr)
+}
+
+func (w *writer) QueueRetry(delay time.Duration) {
+	ctx := context.Background()
+	w.queueRetry.Add(ctx, 1, w.attr)
+	w.retryDelay.Record(ctx, w.dur(delay), w.attr)
+}
+
+func (w *writer) QueueLeak(direction string) {
+	ctx := context.Background()
+	w.queueLeak.Add(ctx, 1, w.attrOf("dir", direction))
+	w.queueSize.Add(ctx, -1, w.attr)
+}
+
+func (w *writer) QueueDeadline() {
+	ctx := context.Background()
+	w.queueDeadline.Add(ctx, 1, w.attr)
+	w.queueSize.Add(ctx, -1, w.attr)
+}
+
+func (w *writer) QueueLost() {
+	ctx := context.Background()
+	w.queueLost.Add(ctx, 1, w.attr)
+	w.queueSize.Add(ctx, -1, w.attr)
+}
+
+func (w *writer) QueueFail(lost bool) {
+	ctx := context.Background()
+	w.queueFail.Add(ctx, 1, w.attr)
+	if lost {
+		w.queueLost.Add(ctx, 1, w.attr)
+	}
+}
+
+func (w *writer) QueueExec(spent time.Duration) {
+	w.queueExec.Record(context.Background(), w.dur(spent), w.attr)
+}
+
+func (w *writer) QueueWait(dur time.Duration) {
+	w.queueWait.Record(context.Background(), w.dur(dur), w.attr)
+}
+
+func (w *writer) QueueSchedule(schedID int) {
+	w.queueSchedule.Record(context.Background(), int64(schedID), w.attr)
+}
+
+func (w *writer) QueueBreaker(state string) {
+	w.queueBreaker.Add(context.Background(), 1, w.attrOf("state", state))
+}
+
+func (w *writer) OverflowPut() {
+	ctx := context.Background()
+	w.overflowIn.Add(ctx, 1, w.attr)
+	w.overflowSize.Add(ctx, 1, w.attr)
+	w.queueSize.Add(ctx, -1, w.attr)
+}
+
+func (w *writer) OverflowPull() {
+	ctx := context.Background()
+	w.overflowOut.Add(ctx, 1, w.attr)
+	w.overflowSize.Add(ctx, -1, w.attr)
+	w.queueSize.Add(ctx, 1, w.attr)
+}
+
+func (w *writer) SubqPut(subq string) {
+	ctx, attr := context.Background(), w.attrOf("subq", subq)
+	w.subqIn.Add(ctx, 1, attr)
+	w.subqSize.Add(ctx, 1, attr)
+}
+
+func (w *writer) SubqPull(subq string) {
+	ctx, attr := context.Background(), w.attrOf("subq", subq)
+	w.subqOut.Add(ctx, 1, attr)
+	w.subqSize.Add(ctx, -1, attr)
+}
+
+func (w *writer) SubqLeak(subq string) {
+	ctx, attr := context.Background(), w.attrOf("subq", subq)
+	w.subqLeak.Add(ctx, 1, attr)
+	w.subqSize.Add(ctx, -1, attr)
+}
+
+func (w *writer) SubqWait(subq string, dur time.Duration) {
+	w.subqWait.Record(context.Background(), w.dur(dur), w.attrOf("subq", subq))
+}
+
+func (w *writer) setWorkers(c metric.Int64UpDownCounter, val *int64, n int64) {
+	if delta := n - atomic.SwapInt64(val, n); delta != 0 {
+		c.Add(context.Background(), delta, w.attr)
+	}
+}
+
+func (w *writer) addWorkers(c metric.Int64UpDownCounter, val *int64, delta int64) {
+	atomic.AddInt64(val, delta)
+	c.Add(context.Background(), delta, w.attr)
+}
+
+func (w *writer) dur(d time.Duration) float64 {
+	return float64(d.Nanoseconds() / int64(w.prec))
+}
+
+// Get cached attributes of the queue with extra attribute.
+func (w *writer) attrOf(key, value string) metric.MeasurementOption {
+	ck := key + ":" + value
+	if raw, ok := w.attrs.Load(ck); ok {
+		return raw.(metric.MeasurementOption)
+	}
+	attr := metric.WithAttributeSet(attribute.NewSet(attribute.String("queue", w.name), attribute.String(key, value)))
+	raw, _ := w.attrs.LoadOrStore(ck, attr)
+	return raw.(metric.MeasurementOption)
+}
+
+var _ = NewWriter
@@ -0,0 +1,96 @@
+package otel
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestWriter(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	w, err := NewWriter("test", WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+		WithPrecision(time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.WorkerSetup(0, 0, 4)
+	w.WorkerInit(0)
+	w.WorkerInit(1)
+	w.WorkerSleep(1)
+	w.WorkerSetup(1, 1, 2)
+	w.QueuePut()
+	w.QueuePut()
+	w.QueuePut()
+	w.QueuePull()
+	w.QueueLeak("rear")
+	w.QueueExec(time.Millisecond * 15)
+	w.QueueSchedule(2)
+	w.QueueBreaker("open")
+	w.SubqPut("high")
+	w.SubqPut("low")
+	w.SubqPull("high")
+
+	var rm metricdata.ResourceMetrics
+	if err = reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	points := make(map[string]metricdata.Aggregation)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			points[m.Name] = m.Data
+		}
+	}
+	value := func(name string, attrs ...attribute.KeyValue) int64 {
+		set := attribute.NewSet(append([]attribute.KeyValue{attribute.String("queue", "test")}, attrs...)...)
+		switch data := points[name].(type) {
+		case metricdata.Sum[int64]:
+			for _, dp := range data.DataPoints {
+				if dp.Attributes.Equals(&set) {
+					return dp.Value
+				}
+			}
+		case metricdata.Gauge[int64]:
+			for _, dp := range data.DataPoints {
+				if dp.Attributes.Equals(&set) {
+					return dp.Value
+				}
+			}
+		case metricdata.Histogram[float64]:
+			for _, dp := range data.DataPoints {
+				if dp.Attributes.Equals(&set) {
+					return int64(dp.Sum)
+				}
+			}
+		}
+		t.Errorf("metric %s not found", name)
+		return -1
+	}
+	checks := []struct {
+		name  string
+		attrs []attribute.KeyValue
+		need  int64
+	}{
+		{"queue_workers_active", nil, 1},
+		{"queue_workers_sleep", nil, 1},
+		{"queue_workers_idle", nil, 2},
+		{"queue_in", nil, 3},
+		{"queue_out", nil, 1},
+		{"queue_size", nil, 1},
+		{"queue_leak", []attribute.KeyValue{attribute.String("dir", "rear")}, 1},
+		{"queue_exec", nil, 15},
+		{"queue_schedule", nil, 2},
+		{"queue_breaker", []attribute.KeyValue{attribute.String("state", "open")}, 1},
+		{"queue_subq_in", []attribute.KeyValue{attribute.String("subq", "high")}, 1},
+		{"queue_subq_size", []attribute.KeyValue{attribute.String("subq", "high")}, 0},
+		{"queue_subq_size", []attribute.KeyValue{attribute.String("subq", "low")}, 1},
+	}
+	for _, c := range checks {
+		if got := value(c.name, c.attrs...); got != c.need {
+			t.Errorf("%s%v mismatch: need %d, got %d", c.name, c.attrs, c.need, got)
+		}
+	}
+}
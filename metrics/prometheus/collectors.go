@@ -0,0 +1,190 @@
+package prometheus
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// collectors is a set of metrics vectors shared among writers with the same registry, namespace and const labels.
+type collectors struct {
+	key  string
+	reg  prometheus.Registerer
+	refs int
+
+	bkt, bktExec, bktRD []float64
+
+	workerIdle, workerActive, workerSleep, queueSize, queueSchedule, subqSize, overflowSize *prometheus.GaugeVec
+	queueIn, queueOut, queueRetry, queueLeak, queueDeadline, queueLost, queueFail, queueBreaker, overflowIn, overflowOut,
+	subqIn, subqOut, subqLeak *prometheus.CounterVec
+	workerWait, retryDelay, queueExec, queueWait, subqWait *prometheus.HistogramVec
+
+	list []prometheus.Collector
+}
+
+var (
+	cmux sync.Mutex
+	cidx = make(map[prometheus.Registerer]map[string]*collectors)
+)
+
+// ErrBucketsMismatch means that writer requires histograms buckets different from buckets of already registered
+// collectors with the same name.
+var ErrBucketsMismatch = errors.New("histograms buckets mismatch with already registered collectors")
+
+// Get existing or make and register new collectors set for writer.
+func acquireCollectors(w *writer) (*collectors, error) {
+	cmux.Lock()
+	defer cmux.Unlock()
+	key := w.ns + "|" + fmtLabels(w.labels)
+	if c, ok := cidx[w.reg][key]; ok {
+		// Registry can't keep histograms with the same name and different buckets.
+		if !eqBuckets(c.bkt, w.bkt) || !eqBuckets(c.bktExec, w.bktExec) || !eqBuckets(c.bktRD, w.bktRD) {
+			return nil, ErrBucketsMismatch
+		}
+		c.refs++
+		return c, nil
+	}
+	c := &collectors{key: key, reg: w.reg, refs: 1, bkt: w.bkt, bktExec: w.bktExec, bktRD: w.bktRD}
+	c.init(w)
+	if cidx[w.reg] == nil {
+		cidx[w.reg] = make(map[string]*collectors)
+	}
+	cidx[w.reg][key] = c
+	return c, nil
+}
+
+// Remove metrics of the queue and unregister collectors set if it isn't used anymore.
+func releaseCollectors(c *collectors, queue string) {
+	cmux.Lock()
+	defer cmux.Unlock()
+	for _, col := range c.list {
+		switch v := col.(type) {
+		case *prometheus.GaugeVec:
+			v.DeletePartialMatch(prometheus.Labels{"queue": queue})
+		case *prometheus.CounterVec:
+			v.DeletePartialMatch(prometheus.Labels{"queue": queue})
+		case *prometheus.HistogramVec:
+			v.DeletePartialMatch(prometheus.Labels{"queue": queue})
+		}
+	}
+	if c.refs--; c.refs > 0 {
+		return
+	}
+	for _, col := range c.list {
+		c.reg.Unregister(col)
+	}
+	delete(cidx[c.reg], c.key)
+	if len(cidx[c.reg]) == 0 {
+		delete(cidx, c.reg)
+	}
+}
+
+func (c *collectors) init(w *writer) {
+	gauge := func(name, help string, labels ...string) *prometheus.GaugeVec {
+		v := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace:   w.ns,
+			Name:        name,
+			Help:        help,
+			ConstLabels: w.labels,
+		}, labels)
+		return c.register(v).(*prometheus.GaugeVec)
+	}
+	counter := func(name, help string, labels ...string) *prometheus.CounterVec {
+		v := prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   w.ns,
+			Name:        name,
+			Help:        help,
+			ConstLabels: w.labels,
+		}, labels)
+		return c.register(v).(*prometheus.CounterVec)
+	}
+	histogram := func(name, help string, buckets []float64, labels ...string) *prometheus.HistogramVec {
+		v := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   w.ns,
+			Name:        name,
+			Help:        help,
+			ConstLabels: w.labels,
+			Buckets:     buckets,
+		}, labels)
+		return c.register(v).(*prometheus.HistogramVec)
+	}
+
+	c.workerIdle = gauge("queue_workers_idle", "Indicates how many workers idle.", "queue")
+	c.workerActive = gauge("queue_workers_active", "Indicates how many workers active.", "queue")
+	c.workerSleep = gauge("queue_workers_sleep", "Indicates how many workers sleep.", "queue")
+	c.queueSize = gauge("queue_size", "Actual queue size.", "queue")
+	c.queueSchedule = gauge("queue_schedule", "Actual schedule rule ID (-1 if no rule hits).", "queue")
+
+	c.queueIn = counter("queue_in", "How many items comes to the queue.", "queue")
+	c.queueOut = counter("queue_out", "How many items leaves queue.", "queue")
+	c.queueRetry = counter("queue_retry", "How many retries occurs.", "queue")
+	c.queueLeak = counter("queue_leak", "How many items dropped on the floor due to queue is full.", "queue", "dir")
+	c.queueDeadline = counter("queue_deadline", "How many processing skips due to deadline.", "queue")
+	c.queueLost = counter("queue_lost", "How many items throw to the trash due to force close.", "queue")
+	c.queueFail = counter("queue_fail", "How many items failed after all processing attempts.", "queue")
+	c.queueBreaker = counter("queue_breaker", "How many times circuit breaker switches to the state.", "queue", "state")
+
+	c.workerWait = histogram("queue_wait", "How long worker waits due to delayed execution.", w.bkt, "queue")
+	c.retryDelay = histogram("queue_retry_delay", "How long worker waits between retry attempts.", w.bktRD, "queue")
+	c.queueExec = histogram("queue_exec", "How long queue executes the job.", w.bktExec, "queue")
+	c.queueWait = histogram("queue_wait_time", "How long item waits in the queue before worker takes it.", w.bkt,
+		"queue")
+
+	c.subqSize = gauge("queue_subq_size", "Actual queue size.", "queue", "subq")
+	c.subqIn = counter("queue_subq_in", "How many items comes to the sub-queue.", "queue", "subq")
+	c.subqOut = counter("queue_subq_out", "How many items leaves sub-queue.", "queue", "subq")
+	c.subqLeak = counter("queue_subq_leak", "How many items dropped on the floor due to sub-queue is full.",
+		"queue", "subq")
+	c.subqWait = histogram("queue_subq_wait_time", "How long item waits in the sub-queue before forwarding to egress.",
+		w.bkt, "queue", "subq")
+
+	c.overflowSize = gauge("queue_overflow_size", "Actual overflow queue size.", "queue")
+	c.overflowIn = counter("queue_overflow_in", "How many items spilled from the full queue to overflow queue.", "queue")
+	c.overflowOut = counter("queue_overflow_out", "How many items returned from overflow queue to the queue.", "queue")
+}
+
+// Register collector or get already registered one.
+func (c *collectors) register(col prometheus.Collector) prometheus.Collector {
+	if err := c.reg.Register(col); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if !errors.As(err, &are) {
+			panic(err)
+		}
+		col = are.ExistingCollector
+	}
+	c.list = append(c.list, col)
+	return col
+}
+
+// Format labels in stable order.
+func fmtLabels(labels prometheus.Labels) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var buf strings.Builder
+	for _, k := range keys {
+		_, _ = buf.WriteString(k)
+		_ = buf.WriteByte('=')
+		_, _ = buf.WriteString(labels[k])
+		_ = buf.WriteByte(',')
+	}
+	return buf.String()
+}
+
+// Check buckets equality.
+func eqBuckets(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := 0; i < len(a); i++ {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -1,6 +1,10 @@
 package prometheus
 
-import "time"
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
 
 type Option func(writer *writer)
 
@@ -9,3 +13,46 @@ func WithPrecision(precision time.Duration) Option {
 		writer.prec = precision
 	}
 }
+
+// WithRegistry sets registry to register collectors.
+// If this option omit prometheus.DefaultRegisterer will use instead.
+func WithRegistry(reg prometheus.Registerer) Option {
+	return func(writer *writer) {
+		writer.reg = reg
+	}
+}
+
+// WithNamespace sets namespace (prefix) of metrics names.
+func WithNamespace(ns string) Option {
+	return func(writer *writer) {
+		writer.ns = ns
+	}
+}
+
+// WithConstLabels sets labels that will add to all metrics.
+func WithConstLabels(labels prometheus.Labels) Option {
+	return func(writer *writer) {
+		writer.labels = labels
+	}
+}
+
+// WithBuckets sets buckets of all histograms.
+func WithBuckets(buckets []float64) Option {
+	return func(writer *writer) {
+		writer.bkt = buckets
+	}
+}
+
+// WithExecBuckets sets buckets of queue_exec histogram. Overwrites WithBuckets option.
+func WithExecBuckets(buckets []float64) Option {
+	return func(writer *writer) {
+		writer.bktExec = buckets
+	}
+}
+
+// WithRetryDelayBuckets sets buckets of queue_retry_delay histogram. Overwrites WithBuckets option.
+func WithRetryDelayBuckets(buckets []float64) Option {
+	return func(writer *writer) {
+		writer.bktRD = buckets
+	}
+}
@@ -1,6 +1,7 @@
 package prometheus
 
 import (
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -19,110 +20,40 @@ type Writer interface {
 	QueueLeak(direction string)
 	QueueDeadline()
 	QueueLost()
+	QueueFail(lost bool)
 	QueueExec(spent time.Duration)
+	QueueWait(dur time.Duration)
+	QueueSchedule(schedID int)
+	QueueBreaker(state string)
+	OverflowPut()
+	OverflowPull()
 	SubqPut(subq string)
 	SubqPull(subq string)
 	SubqLeak(subq string)
+	SubqWait(subq string, dur time.Duration)
+	Unregister()
 }
 
 // writer is a Prometheus implementation of queue.MetricsWriter.
 type writer struct {
 	name string
 	prec time.Duration
-}
-
-var (
-	promQueueSize, promSubqSize, promWorkerIdle, promWorkerActive, promWorkerSleep *prometheus.GaugeVec
-	promQueueIn, promQueueOut, promQueueRetry, promQueueLeak, promQueueDeadline, promQueueLost,
-	promSubqIn, promSubqOut, promSubqLeak *prometheus.CounterVec
 
-	promWorkerWait, promRetryDelay, promQueueExec *prometheus.HistogramVec
-)
+	reg     prometheus.Registerer
+	ns      string
+	labels  prometheus.Labels
+	bkt     []float64
+	bktExec []float64
+	bktRD   []float64
 
-func init() {
-	promWorkerIdle = prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "queue_workers_idle",
-		Help: "Indicates how many workers idle.",
-	}, []string{"queue"})
-	promWorkerActive = prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "queue_workers_active",
-		Help: "Indicates how many workers active.",
-	}, []string{"queue"})
-	promWorkerSleep = prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "queue_workers_sleep",
-		Help: "Indicates how many workers sleep.",
-	}, []string{"queue"})
-
-	promQueueSize = prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "queue_size",
-		Help: "Actual queue size.",
-	}, []string{"queue"})
-
-	promQueueIn = prometheus.NewCounterVec(prometheus.CounterOpts{
-		Name: "queue_in",
-		Help: "How many items comes to the queue.",
-	}, []string{"queue"})
-	promQueueOut = prometheus.NewCounterVec(prometheus.CounterOpts{
-		Name: "queue_out",
-		Help: "How many items leaves queue.",
-	}, []string{"queue"})
-	promQueueRetry = prometheus.NewCounterVec(prometheus.CounterOpts{
-		Name: "queue_retry",
-		Help: "How many retries occurs.",
-	}, []string{"queue"})
-	promQueueLeak = prometheus.NewCounterVec(prometheus.CounterOpts{
-		Name: "queue_leak",
-		Help: "How many items dropped on the floor due to queue is full.",
-	}, []string{"queue", "dir"})
-	promQueueDeadline = prometheus.NewCounterVec(prometheus.CounterOpts{
-		Name: "queue_deadline",
-		Help: "How many processing skips due to deadline.",
-	}, []string{"queue"})
-	promQueueLost = prometheus.NewCounterVec(prometheus.CounterOpts{
-		Name: "queue_lost",
-		Help: "How many items throw to the trash due to force close.",
-	}, []string{"queue"})
-
-	buckets := append(prometheus.DefBuckets, []float64{15, 20, 30, 40, 50, 100, 150, 200, 250, 500, 1000, 1500, 2000, 3000, 5000}...)
-	promWorkerWait = prometheus.NewHistogramVec(prometheus.HistogramOpts{
-		Name:    "queue_wait",
-		Help:    "How long worker waits due to delayed execution.",
-		Buckets: buckets,
-	}, []string{"queue"})
-	promRetryDelay = prometheus.NewHistogramVec(prometheus.HistogramOpts{
-		Name:    "queue_retry_delay",
-		Help:    "How long worker waits between retry attempts.",
-		Buckets: buckets,
-	}, []string{"queue"})
-	promQueueExec = prometheus.NewHistogramVec(prometheus.HistogramOpts{
-		Name:    "queue_exec",
-		Help:    "How long queue executes the job.",
-		Buckets: buckets,
-	}, []string{"queue"})
-
-	promSubqSize = prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "queue_subq_size",
-		Help: "Actual queue size.",
-	}, []string{"queue", "subq"})
-	promSubqIn = prometheus.NewCounterVec(prometheus.CounterOpts{
-		Name: "queue_subq_in",
-		Help: "How many items comes to the sub-queue.",
-	}, []string{"queue", "subq"})
-	promSubqOut = prometheus.NewCounterVec(prometheus.CounterOpts{
-		Name: "queue_subq_out",
-		Help: "How many items leaves sub-queue.",
-	}, []string{"queue", "subq"})
-	promSubqLeak = prometheus.NewCounterVec(prometheus.CounterOpts{
-		Name: "queue_subq_leak",
-		Help: "How many items dropped on the floor due to sub-queue is full.",
-	}, []string{"queue", "subq"})
-
-	prometheus.MustRegister(promWorkerIdle, promWorkerActive, promWorkerSleep, promQueueSize,
-		promQueueIn, promQueueOut, promQueueRetry, promQueueLeak, promQueueLost, promQueueDeadline,
-		promWorkerWait, promRetryDelay, promQueueExec,
-		promSubqSize, promSubqIn, promSubqOut, promSubqLeak)
+	c    *collectors
+	once *sync.Once // release guard
 }
 
+// Default histograms buckets.
+var defaultBuckets = append(append([]float64(nil), prometheus.DefBuckets...),
+	15, 20, 30, 40, 50, 100, 150, 200, 250, 500, 1000, 1500, 2000, 3000, 5000)
+
 // NewPrometheusMetrics is an old constructor.
 // Deprecated: use NewWriter instead.
 func NewPrometheusMetrics(name string) Writer {
@@ -136,105 +67,165 @@ func NewPrometheusMetricsWP(name string, precision time.Duration) Writer {
 }
 
 // NewWriter makes a new instance of metrics writer.
+//
+// Writers with the same registry, namespace and const labels share collectors, so they must use the same histograms
+// buckets, otherwise ErrBucketsMismatch occurs. Registration error (except prometheus.AlreadyRegisteredError) causes
+// panic like prometheus.MustRegister does.
 func NewWriter(name string, options ...Option) Writer {
-	mw := &writer{name: name}
+	mw := &writer{name: name, once: &sync.Once{}}
 	for _, fn := range options {
 		fn(mw)
 	}
 	if mw.prec == 0 {
 		mw.prec = time.Nanosecond
 	}
+	if mw.reg == nil {
+		mw.reg = prometheus.DefaultRegisterer
+	}
+	if mw.bkt == nil {
+		mw.bkt = defaultBuckets
+	}
+	if mw.bktExec == nil {
+		mw.bktExec = mw.bkt
+	}
+	if mw.bktRD == nil {
+		mw.bktRD = mw.bkt
+	}
+	var err error
+	if mw.c, err = acquireCollectors(mw); err != nil {
+		panic(err)
+	}
 	return mw
 }
 
+// Unregister removes metrics of the queue and unregisters collectors if no writer uses them anymore.
+// Queue calls it itself after close, so call it manually only if writer is used apart from the queue. Repeated calls
+// are no-op. Writer must not be used after that call.
+func (w writer) Unregister() {
+	w.once.Do(func() {
+		releaseCollectors(w.c, w.name)
+	})
+}
+
 func (w writer) WorkerSetup(active, sleep, stop uint) {
-	promWorkerActive.DeleteLabelValues(w.name)
-	promWorkerSleep.DeleteLabelValues(w.name)
-	promWorkerIdle.DeleteLabelValues(w.name)
+	w.c.workerActive.DeleteLabelValues(w.name)
+	w.c.workerSleep.DeleteLabelValues(w.name)
+	w.c.workerIdle.DeleteLabelValues(w.name)
 
-	promWorkerActive.WithLabelValues(w.name).Add(float64(active))
-	promWorkerSleep.WithLabelValues(w.name).Add(float64(sleep))
-	promWorkerIdle.WithLabelValues(w.name).Add(float64(stop))
+	w.c.workerActive.WithLabelValues(w.name).Add(float64(active))
+	w.c.workerSleep.WithLabelValues(w.name).Add(float64(sleep))
+	w.c.workerIdle.WithLabelValues(w.name).Add(float64(stop))
 }
 
 func (w writer) WorkerInit(_ uint32) {
-	promWorkerActive.WithLabelValues(w.name).Inc()
-	promWorkerIdle.WithLabelValues(w.name).Add(-1)
+	w.c.workerActive.WithLabelValues(w.name).Inc()
+	w.c.workerIdle.WithLabelValues(w.name).Add(-1)
 }
 
 func (w writer) WorkerSleep(_ uint32) {
-	promWorkerSleep.WithLabelValues(w.name).Inc()
-	promWorkerActive.WithLabelValues(w.name).Add(-1)
+	w.c.workerSleep.WithLabelValues(w.name).Inc()
+	w.c.workerActive.WithLabelValues(w.name).Add(-1)
 }
 
 func (w writer) WorkerWakeup(_ uint32) {
-	promWorkerActive.WithLabelValues(w.name).Inc()
-	promWorkerSleep.WithLabelValues(w.name).Add(-1)
+	w.c.workerActive.WithLabelValues(w.name).Inc()
+	w.c.workerSleep.WithLabelValues(w.name).Add(-1)
 }
 
 func (w writer) WorkerWait(_ uint32, delay time.Duration) {
-	promWorkerWait.WithLabelValues(w.name).Observe(float64(delay.Nanoseconds() / int64(w.prec)))
-}
-
-func (w writer) WorkerStop(_ uint32, force bool, status string) {
-	promWorkerIdle.WithLabelValues(w.name).Inc()
-	if force {
-		switch status {
-		case "active":
-			promWorkerActive.WithLabelValues(w.name).Add(-1)
-		case "sleep":
-			promWorkerSleep.WithLabelValues(w.name).Add(-1)
-		}
-	} else {
-		promWorkerSleep.WithLabelValues(w.name).Add(-1)
+	w.c.workerWait.WithLabelValues(w.name).Observe(float64(delay.Nanoseconds() / int64(w.prec)))
+}
+
+func (w writer) WorkerStop(_ uint32, _ bool, status string) {
+	w.c.workerIdle.WithLabelValues(w.name).Inc()
+	switch status {
+	case "active":
+		w.c.workerActive.WithLabelValues(w.name).Add(-1)
+	case "sleep":
+		w.c.workerSleep.WithLabelValues(w.name).Add(-1)
 	}
 }
 
 func (w writer) QueuePut() {
-	promQueueIn.WithLabelValues(w.name).Inc()
-	promQueueSize.WithLabelValues(w.name).Inc()
+	w.c.queueIn.WithLabelValues(w.name).Inc()
+	w.c.queueSize.WithLabelValues(w.name).Inc()
 }
 
 func (w writer) QueuePull() {
-	promQueueOut.WithLabelValues(w.name).Inc()
-	promQueueSize.WithLabelValues(w.name).Dec()
+	w.c.queueOut.WithLabelValues(w.name).Inc()
+	w.c.queueSize.WithLabelValues(w.name).Dec()
 }
 
 func (w writer) QueueRetry(delay time.Duration) {
-	promQueueRetry.WithLabelValues(w.name).Inc()
-	promRetryDelay.WithLabelValues(w.name).Observe(float64(delay.Nanoseconds() / int64(w.prec)))
+	w.c.queueRetry.WithLabelValues(w.name).Inc()
+	w.c.retryDelay.WithLabelValues(w.name).Observe(float64(delay.Nanoseconds() / int64(w.prec)))
 }
 
 func (w writer) QueueLeak(direction string) {
-	promQueueLeak.WithLabelValues(w.name, direction).Inc()
-	promQueueSize.WithLabelValues(w.name).Dec()
+	w.c.queueLeak.WithLabelValues(w.name, direction).Inc()
+	w.c.queueSize.WithLabelValues(w.name).Dec()
 }
 
 func (w writer) QueueDeadline() {
-	promQueueDeadline.WithLabelValues(w.name).Inc()
-	promQueueSize.WithLabelValues(w.name).Dec()
+	w.c.queueDeadline.WithLabelValues(w.name).Inc()
+	w.c.queueSize.WithLabelValues(w.name).Dec()
 }
 
 func (w writer) QueueLost() {
-	promQueueLost.WithLabelValues(w.name).Inc()
-	promQueueSize.WithLabelValues(w.name).Dec()
+	w.c.queueLost.WithLabelValues(w.name).Inc()
+	w.c.queueSize.WithLabelValues(w.name).Dec()
+}
+
+func (w writer) QueueFail(lost bool) {
+	w.c.queueFail.WithLabelValues(w.name).Inc()
+	if lost {
+		w.c.queueLost.WithLabelValues(w.name).Inc()
+	}
 }
 
 func (w writer) QueueExec(spent time.Duration) {
-	promQueueExec.WithLabelValues(w.name).Observe(float64(spent.Nanoseconds() / int64(w.prec)))
+	w.c.queueExec.WithLabelValues(w.name).Observe(float64(spent.Nanoseconds() / int64(w.prec)))
+}
+
+func (w writer) QueueWait(dur time.Duration) {
+	w.c.queueWait.WithLabelValues(w.name).Observe(float64(dur.Nanoseconds() / int64(w.prec)))
+}
+
+func (w writer) QueueSchedule(schedID int) {
+	w.c.queueSchedule.WithLabelValues(w.name).Set(float64(schedID))
+}
+
+func (w writer) QueueBreaker(state string) {
+	w.c.queueBreaker.WithLabelValues(w.name, state).Inc()
+}
+
+func (w writer) OverflowPut() {
+	w.c.overflowIn.WithLabelValues(w.name).Inc()
+	w.c.overflowSize.WithLabelValues(w.name).Inc()
+	w.c.queueSize.WithLabelValues(w.name).Dec()
+}
+
+func (w writer) OverflowPull() {
+	w.c.overflowOut.WithLabelValues(w.name).Inc()
+	w.c.overflowSize.WithLabelValues(w.name).Dec()
+	w.c.queueSize.WithLabelValues(w.name).Inc()
 }
 
 func (w writer) SubqPut(subq string) {
-	promSubqIn.WithLabelValues(w.name, subq).Inc()
-	promSubqSize.WithLabelValues(w.name, subq).Inc()
+	w.c.subqIn.WithLabelValues(w.name, subq).Inc()
+	w.c.subqSize.WithLabelValues(w.name, subq).Inc()
 }
 
 func (w writer) SubqPull(subq string) {
-	promSubqOut.WithLabelValues(w.name, subq).Inc()
-	promSubqSize.WithLabelValues(w.name, subq).Dec()
+	w.c.subqOut.WithLabelValues(w.name, subq).Inc()
+	w.c.subqSize.WithLabelValues(w.name, subq).Dec()
 }
 
 func (w writer) SubqLeak(subq string) {
-	promSubqLeak.WithLabelValues(w.name, subq).Inc()
-	promSubqSize.WithLabelValues(w.name, subq).Dec()
+	w.c.subqLeak.WithLabelValues(w.name, subq).Inc()
+	w.c.subqSize.WithLabelValues(w.name, subq).Dec()
+}
+
+func (w writer) SubqWait(subq string, dur time.Duration) {
+	w.c.subqWait.WithLabelValues(w.name, subq).Observe(float64(dur.Nanoseconds() / int64(w.prec)))
 }
@@ -0,0 +1,88 @@
+package prometheus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestWriter(t *testing.T) {
+	t.Run("registry", func(t *testing.T) {
+		reg := prometheus.NewRegistry()
+		opts := []Option{WithRegistry(reg), WithNamespace("app"), WithConstLabels(prometheus.Labels{"env": "test"}),
+			WithExecBuckets([]float64{1, 10, 100}), WithPrecision(time.Millisecond)}
+		w1, w2 := NewWriter("q1", opts...), NewWriter("q2", opts...)
+		w1.QueuePut()
+		w1.QueuePut()
+		w2.QueuePut()
+		w1.QueueExec(time.Millisecond * 5)
+
+		c := w1.(*writer).c
+		if c != w2.(*writer).c {
+			t.Error("writers must share collectors")
+		}
+		if v := testutil.ToFloat64(c.queueIn.WithLabelValues("q1")); v != 2 {
+			t.Errorf("q1 in mismatch: need 2, got %f", v)
+		}
+		if v := testutil.ToFloat64(c.queueIn.WithLabelValues("q2")); v != 1 {
+			t.Errorf("q2 in mismatch: need 1, got %f", v)
+		}
+		mfs, err := reg.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var found bool
+		for _, mf := range mfs {
+			if mf.GetName() == "app_queue_exec" {
+				found = true
+				m := mf.GetMetric()[0]
+				if len(m.GetHistogram().GetBucket()) != 3 {
+					t.Errorf("exec buckets mismatch: %v", m.GetHistogram().GetBucket())
+				}
+				if lp := m.GetLabel(); len(lp) != 2 || lp[0].GetName() != "env" || lp[0].GetValue() != "test" {
+					t.Errorf("labels mismatch: %v", lp)
+				}
+			}
+		}
+		if !found {
+			t.Error("app_queue_exec not found")
+		}
+
+		w1.Unregister()
+		w1.Unregister()
+		if n := testutil.CollectAndCount(c.queueIn); n != 1 {
+			t.Errorf("series count mismatch after unregister: need 1, got %d", n)
+		}
+		w2.Unregister()
+		if mfs, _ = reg.Gather(); len(mfs) != 0 {
+			t.Errorf("registry must be empty, got %d metrics", len(mfs))
+		}
+		// Registry is clean, so writer may register again.
+		NewWriter("q1", opts...).Unregister()
+	})
+	t.Run("buckets", func(t *testing.T) {
+		reg := prometheus.NewRegistry()
+		w := NewWriter("q1", WithRegistry(reg), WithBuckets([]float64{1, 10}))
+		defer w.Unregister()
+		defer func() {
+			if r := recover(); r != ErrBucketsMismatch {
+				t.Errorf("need ErrBucketsMismatch panic, got %v", r)
+			}
+		}()
+		NewWriter("q2", WithRegistry(reg), WithBuckets([]float64{1, 100}))
+	})
+	t.Run("already registered", func(t *testing.T) {
+		reg := prometheus.NewRegistry()
+		ext := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "queue_in", Help: "How many items comes to the queue."},
+			[]string{"queue"})
+		reg.MustRegister(ext)
+		w := NewWriter("q", WithRegistry(reg))
+		w.QueuePut()
+		if v := testutil.ToFloat64(ext.WithLabelValues("q")); v != 1 {
+			t.Errorf("existing collector must be reused, got %f", v)
+		}
+		w.Unregister()
+	})
+}
@@ -19,10 +19,17 @@ type Writer interface {
 	QueueLeak(direction string)
 	QueueDeadline()
 	QueueLost()
+	QueueFail(lost bool)
 	QueueExec(spent time.Duration)
+	QueueWait(dur time.Duration)
+	QueueSchedule(schedID int)
+	QueueBreaker(state string)
+	OverflowPut()
+	OverflowPull()
 	SubqPut(subq string)
 	SubqPull(subq string)
 	SubqLeak(subq string)
+	SubqWait(subq string, dur time.Duration)
 }
 
 // writer is a VictoriaMetrics implementation of queue.MetricsWriter.
@@ -68,16 +75,12 @@ func (w writer) WorkerWait(_ uint32, delay time.Duration) {
 	vmchain.Histogram("queue_wait").WithLabel("queue", w.name).Update(float64(delay.Nanoseconds() / int64(w.prec)))
 }
 
-func (w writer) WorkerStop(_ uint32, force bool, status string) {
+func (w writer) WorkerStop(_ uint32, _ bool, status string) {
 	vmchain.Gauge("queue_workers_idle", nil).WithLabel("queue", w.name).Inc()
-	if force {
-		switch status {
-		case "active":
-			vmchain.Gauge("queue_workers_active", nil).WithLabel("queue", w.name).Dec()
-		case "sleep":
-			vmchain.Gauge("queue_workers_sleep", nil).WithLabel("queue", w.name).Dec()
-		}
-	} else {
+	switch status {
+	case "active":
+		vmchain.Gauge("queue_workers_active", nil).WithLabel("queue", w.name).Dec()
+	case "sleep":
 		vmchain.Gauge("queue_workers_sleep", nil).WithLabel("queue", w.name).Dec()
 	}
 }
@@ -112,10 +115,41 @@ func (w writer) QueueLost() {
 	vmchain.Gauge("queue_size", nil).WithLabel("queue", w.name).Dec()
 }
 
+func (w writer) QueueFail(lost bool) {
+	vmchain.Counter("queue_fail").WithLabel("queue", w.name).Inc()
+	if lost {
+		vmchain.Counter("queue_lost").WithLabel("queue", w.name).Inc()
+	}
+}
+
 func (w writer) QueueExec(spent time.Duration) {
 	vmchain.Histogram("queue_exec").WithLabel("queue", w.name).Update(float64(spent.Nanoseconds() / int64(w.prec)))
 }
 
+func (w writer) QueueWait(dur time.Duration) {
+	vmchain.Histogram("queue_wait_time").WithLabel("queue", w.name).Update(float64(dur.Nanoseconds() / int64(w.prec)))
+}
+
+func (w writer) QueueSchedule(schedID int) {
+	vmchain.Gauge("queue_schedule", nil).WithLabel("queue", w.name).Set(float64(schedID))
+}
+
+func (w writer) QueueBreaker(state string) {
+	vmchain.Counter("queue_breaker").WithLabel("queue", w.name).WithLabel("state", state).Inc()
+}
+
+func (w writer) OverflowPut() {
+	vmchain.Counter("queue_overflow_in").WithLabel("queue", w.name).Inc()
+	vmchain.Gauge("queue_overflow_size", nil).WithLabel("queue", w.name).Inc()
+	vmchain.Gauge("queue_size", nil).WithLabel("queue", w.name).Dec()
+}
+
+func (w writer) OverflowPull() {
+	vmchain.Counter("queue_overflow_out").WithLabel("queue", w.name).Inc()
+	vmchain.Gauge("queue_overflow_size", nil).WithLabel("queue", w.name).Dec()
+	vmchain.Gauge("queue_size", nil).WithLabel("queue", w.name).Inc()
+}
+
 func (w writer) SubqPut(subq string) {
 	vmchain.Counter("queue_subq_in").WithLabel("queue", w.name).WithLabel("subq", subq).Inc()
 	vmchain.Gauge("queue_subq_size", nil).WithLabel("queue", w.name).WithLabel("subq", subq).Inc()
@@ -131,4 +165,9 @@ func (w writer) SubqLeak(subq string) {
 	vmchain.Gauge("queue_subq_size", nil).WithLabel("queue", w.name).WithLabel("subq", subq).Dec()
 }
 
+func (w writer) SubqWait(subq string, dur time.Duration) {
+	vmchain.Histogram("queue_subq_wait_time").WithLabel("queue", w.name).WithLabel("subq", subq).
+		Update(float64(dur.Nanoseconds() / int64(w.prec)))
+}
+
 var _ = NewWriter
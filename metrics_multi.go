@@ -0,0 +1,163 @@
+package queue
+
+import "time"
+
+// MultiWriter fans out all metrics calls to several writers.
+//
+// Usage example:
+//
+//	conf := Config{
+//		...
+//		MetricsWriter: MultiWriter{prometheus.NewWriter("example"), NewStatsWriter()},
+//		...
+//	}
+type MultiWriter []MetricsWriter
+
+func (w MultiWriter) WorkerSetup(active, sleep, stop uint) {
+	for i := 0; i < len(w); i++ {
+		w[i].WorkerSetup(active, sleep, stop)
+	}
+}
+
+func (w MultiWriter) WorkerInit(idx uint32) {
+	for i := 0; i < len(w); i++ {
+		w[i].WorkerInit(idx)
+	}
+}
+
+func (w MultiWriter) WorkerSleep(idx uint32) {
+	for i := 0; i < len(w); i++ {
+		w[i].WorkerSleep(idx)
+	}
+}
+
+func (w MultiWriter) WorkerWakeup(idx uint32) {
+	for i := 0; i < len(w); i++ {
+		w[i].WorkerWakeup(idx)
+	}
+}
+
+func (w MultiWriter) WorkerWait(idx uint32, dur time.Duration) {
+	for i := 0; i < len(w); i++ {
+		w[i].WorkerWait(idx, dur)
+	}
+}
+
+func (w MultiWriter) WorkerStop(idx uint32, force bool, status string) {
+	for i := 0; i < len(w); i++ {
+		w[i].WorkerStop(idx, force, status)
+	}
+}
+
+func (w MultiWriter) QueuePut() {
+	for i := 0; i < len(w); i++ {
+		w[i].QueuePut()
+	}
+}
+
+func (w MultiWriter) QueuePull() {
+	for i := 0; i < len(w); i++ {
+		w[i].QueuePull()
+	}
+}
+
+func (w MultiWriter) QueueRetry(delay time.Duration) {
+	for i := 0; i < len(w); i++ {
+		w[i].QueueRetry(delay)
+	}
+}
+
+func (w MultiWriter) QueueLeak(direction string) {
+	for i := 0; i < len(w); i++ {
+		w[i].QueueLeak(direction)
+	}
+}
+
+func (w MultiWriter) QueueDeadline() {
+	for i := 0; i < len(w); i++ {
+		w[i].QueueDeadline()
+	}
+}
+
+func (w MultiWriter) QueueLost() {
+	for i := 0; i < len(w); i++ {
+		w[i].QueueLost()
+	}
+}
+
+func (w MultiWriter) QueueFail(lost bool) {
+	for i := 0; i < len(w); i++ {
+		w[i].QueueFail(lost)
+	}
+}
+
+func (w MultiWriter) QueueExec(spent time.Duration) {
+	for i := 0; i < len(w); i++ {
+		w[i].QueueExec(spent)
+	}
+}
+
+func (w MultiWriter) QueueWait(dur time.Duration) {
+	for i := 0; i < len(w); i++ {
+		w[i].QueueWait(dur)
+	}
+}
+
+func (w MultiWriter) QueueSchedule(schedID int) {
+	for i := 0; i < len(w); i++ {
+		w[i].QueueSchedule(schedID)
+	}
+}
+
+func (w MultiWriter) QueueBreaker(state string) {
+	for i := 0; i < len(w); i++ {
+		w[i].QueueBreaker(state)
+	}
+}
+
+func (w MultiWriter) OverflowPut() {
+	for i := 0; i < len(w); i++ {
+		w[i].OverflowPut()
+	}
+}
+
+func (w MultiWriter) OverflowPull() {
+	for i := 0; i < len(w); i++ {
+		w[i].OverflowPull()
+	}
+}
+
+func (w MultiWriter) SubqPut(subq string) {
+	for i := 0; i < len(w); i++ {
+		w[i].SubqPut(subq)
+	}
+}
+
+func (w MultiWriter) SubqPull(subq string) {
+	for i := 0; i < len(w); i++ {
+		w[i].SubqPull(subq)
+	}
+}
+
+func (w MultiWriter) SubqLeak(subq string) {
+	for i := 0; i < len(w); i++ {
+		w[i].SubqLeak(subq)
+	}
+}
+
+func (w MultiWriter) SubqWait(subq string, dur time.Duration) {
+	for i := 0; i < len(w); i++ {
+		w[i].SubqWait(subq, dur)
+	}
+}
+
+var _ MetricsWriter = MultiWriter(nil)
+
+// Unregister forwards the call to writers that implement it.
+func (w MultiWriter) Unregister() {
+	for i := 0; i < len(w); i++ {
+		if mu, ok := w[i].(metricsUnregisterer); ok {
+			mu.Unregister()
+		}
+	}
+}
@@ -0,0 +1,395 @@
+package queue
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Default histogram buckets (upper bounds) of StatsWriter.
+var defaultStatsBuckets = []time.Duration{
+	time.Microsecond, time.Microsecond * 5, time.Microsecond * 10, time.Microsecond * 50, time.Microsecond * 100,
+	time.Microsecond * 500, time.Millisecond, time.Millisecond * 5, time.Millisecond * 10, time.Millisecond * 50,
+	time.Millisecond * 100, time.Millisecond * 500, time.Second, time.Second * 5, time.Second * 10,
+}
+
+// StatsWriter is an in-memory implementation of MetricsWriter.
+//
+// It aggregates counters and durations histograms locally, so it's useful for tests and debug endpoints. Use it
+// together with other writers via MultiWriter.
+type StatsWriter struct {
+	bkt []time.Duration
+
+	active, sleep, idle, size, sched int64
+	in, out, retry, deadline, lost   uint64
+	fail                             uint64
+	ovIn, ovOut                      uint64
+	ovSize                           int64
+
+	mux     sync.Mutex
+	leak    map[string]uint64
+	breaker map[string]uint64
+	subq    map[string]*SubqStats
+	exec    histogram
+	wait    histogram
+	rdelay  histogram
+	qwait   histogram
+	swait   map[string]*histogram
+}
+
+// Stats is a snapshot of StatsWriter data.
+type Stats struct {
+	WorkersActive, WorkersSleep, WorkersIdle int64
+	// Actual queue size.
+	Size int64
+	// Items counters.
+	In, Out, Retry, Deadline, Lost uint64
+	// Failed after all processing attempts items counter.
+	Fail uint64
+	// Overflow queue counters and actual size.
+	OverflowIn, OverflowOut uint64
+	OverflowSize            int64
+	// Leaks counters by direction.
+	Leak map[string]uint64
+	// Actual schedule rule ID.
+	Schedule int
+	// Circuit breaker switches by state.
+	Breaker map[string]uint64
+	// Sub-queues stats by name.
+	Subq map[string]SubqStats
+	// Histograms of execution time, delayed execution wait and retry delay.
+	Exec, Wait, RetryDelay Histogram
+	// Histogram of item waiting in the queue before worker takes it.
+	QueueWait Histogram
+	// Histograms of item waiting in sub-queues by name.
+	SubqWait map[string]Histogram
+}
+
+// SubqStats describes sub-queue stats.
+type SubqStats struct {
+	Size          int64
+	In, Out, Leak uint64
+}
+
+// Histogram is a snapshot of durations histogram.
+type Histogram struct {
+	Count    uint64
+	Sum      time.Duration
+	Min, Max time.Duration
+	// Buckets contains counts of values less or equal to corresponding upper bound (not cumulative). The last bucket
+	// has zero upper bound and counts values greater than all bounds.
+	Buckets []HistogramBucket
+}
+
+// HistogramBucket describes single histogram bucket.
+type HistogramBucket struct {
+	// Upper bound (inclusive) of the bucket. Zero value means infinity.
+	Le    time.Duration
+	Count uint64
+}
+
+type histogram struct {
+	count    uint64
+	sum      time.Duration
+	min, max time.Duration
+	cnt      []uint64
+}
+
+// NewStatsWriter makes new stats writer with given histogram buckets upper bounds.
+// If buckets omit defaultStatsBuckets (1µs ... 10s) will use instead.
+func NewStatsWriter(buckets ...time.Duration) *StatsWriter {
+	if len(buckets) == 0 {
+		buckets = defaultStatsBuckets
+	}
+	bkt := append([]time.Duration(nil), buckets...)
+	sort.Slice(bkt, func(i, j int) bool { return bkt[i] < bkt[j] })
+	w := &StatsWriter{bkt: bkt, sched: -1}
+	w.reset()
+	return w
+}
+
+// Stats returns snapshot of collected data.
+func (w *StatsWriter) Stats() Stats {
+	s := Stats{
+		WorkersActive: atomic.LoadInt64(&w.active),
+		WorkersSleep:  atomic.LoadInt64(&w.sleep),
+		WorkersIdle:   atomic.LoadInt64(&w.idle),
+		Size:          atomic.LoadInt64(&w.size),
+		In:            atomic.LoadUint64(&w.in),
+		Out:           atomic.LoadUint64(&w.out),
+		Retry:         atomic.LoadUint64(&w.retry),
+		Deadline:      atomic.LoadUint64(&w.deadline),
+		Lost:          atomic.LoadUint64(&w.lost),
+		Fail:          atomic.LoadUint64(&w.fail),
+		OverflowIn:    atomic.LoadUint64(&w.ovIn),
+		OverflowOut:   atomic.LoadUint64(&w.ovOut),
+		OverflowSize:  atomic.LoadInt64(&w.ovSize),
+		Schedule:      int(atomic.LoadInt64(&w.sched)),
+	}
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	s.Leak = make(map[string]uint64, len(w.leak))
+	for k, v := range w.leak {
+		s.Leak[k] = v
+	}
+	s.Breaker = make(map[string]uint64, len(w.breaker))
+	for k, v := range w.breaker {
+		s.Breaker[k] = v
+	}
+	s.Subq = make(map[string]SubqStats, len(w.subq))
+	for k, v := range w.subq {
+		s.Subq[k] = *v
+	}
+	s.Exec, s.Wait, s.RetryDelay = w.exec.snapshot(w.bkt), w.wait.snapshot(w.bkt), w.rdelay.snapshot(w.bkt)
+	s.QueueWait = w.qwait.snapshot(w.bkt)
+	s.SubqWait = make(map[string]Histogram, len(w.swait))
+	for k, v := range w.swait {
+		s.SubqWait[k] = v.snapshot(w.bkt)
+	}
+	return s
+}
+
+// Reset clears all collected data.
+func (w *StatsWriter) Reset() {
+	atomic.StoreInt64(&w.active, 0)
+	atomic.StoreInt64(&w.sleep, 0)
+	atomic.StoreInt64(&w.idle, 0)
+	atomic.StoreInt64(&w.size, 0)
+	atomic.StoreInt64(&w.sched, -1)
+	atomic.StoreUint64(&w.in, 0)
+	atomic.StoreUint64(&w.out, 0)
+	atomic.StoreUint64(&w.retry, 0)
+	atomic.StoreUint64(&w.deadline, 0)
+	atomic.StoreUint64(&w.lost, 0)
+	atomic.StoreUint64(&w.fail, 0)
+	atomic.StoreUint64(&w.ovIn, 0)
+	atomic.StoreUint64(&w.ovOut, 0)
+	atomic.StoreInt64(&w.ovSize, 0)
+	w.mux.Lock()
+	w.reset()
+	w.mux.Unlock()
+}
+
+func (w *StatsWriter) reset() {
+	w.leak = make(map[string]uint64)
+	w.breaker = make(map[string]uint64)
+	w.subq = make(map[string]*SubqStats)
+	w.exec = histogram{cnt: make([]uint64, len(w.bkt)+1)}
+	w.wait = histogram{cnt: make([]uint64, len(w.bkt)+1)}
+	w.rdelay = histogram{cnt: make([]uint64, len(w.bkt)+1)}
+	w.qwait = histogram{cnt: make([]uint64, len(w.bkt)+1)}
+	w.swait = make(map[string]*histogram)
+}
+
+func (w *StatsWriter) WorkerSetup(active, sleep, stop uint) {
+	atomic.StoreInt64(&w.active, int64(active))
+	atomic.StoreInt64(&w.sleep, int64(sleep))
+	atomic.StoreInt64(&w.idle, int64(stop))
+}
+
+func (w *StatsWriter) WorkerInit(_ uint32) {
+	atomic.AddInt64(&w.active, 1)
+	atomic.AddInt64(&w.idle, -1)
+}
+
+func (w *StatsWriter) WorkerSleep(_ uint32) {
+	atomic.AddInt64(&w.sleep, 1)
+	atomic.AddInt64(&w.active, -1)
+}
+
+func (w *StatsWriter) WorkerWakeup(_ uint32) {
+	atomic.AddInt64(&w.active, 1)
+	atomic.AddInt64(&w.sleep, -1)
+}
+
+func (w *StatsWriter) WorkerWait(_ uint32, dur time.Duration) {
+	w.mux.Lock()
+	w.wait.observe(w.bkt, dur)
+	w.mux.Unlock()
+}
+
+func (w *StatsWriter) WorkerStop(_ uint32, _ bool, status string) {
+	atomic.AddInt64(&w.idle, 1)
+	switch status {
+	case "active":
+		atomic.AddInt64(&w.active, -1)
+	case "sleep":
+		atomic.AddInt64(&w.sleep, -1)
+	}
+}
+
+func (w *StatsWriter) QueuePut() {
+	atomic.AddUint64(&w.in, 1)
+	atomic.AddInt64(&w.size, 1)
+}
+
+func (w *StatsWriter) QueuePull() {
+	atomic.AddUint64(&w.out, 1)
+	atomic.AddInt64(&w.size, -1)
+}
+
+func (w *StatsWriter) QueueRetry(delay time.Duration) {
+	atomic.AddUint64(&w.retry, 1)
+	w.mux.Lock()
+	w.rdelay.observe(w.bkt, delay)
+	w.mux.Unlock()
+}
+
+func (w *StatsWriter) QueueLeak(direction string) {
+	atomic.AddInt64(&w.size, -1)
+	w.mux.Lock()
+	w.leak[direction]++
+	w.mux.Unlock()
+}
+
+func (w *StatsWriter) QueueDeadline() {
+	atomic.AddUint64(&w.deadline, 1)
+	atomic.AddInt64(&w.size, -1)
+}
+
+func (w *StatsWriter) QueueLost() {
+	atomic.AddUint64(&w.lost, 1)
+	atomic.AddInt64(&w.size, -1)
+}
+
+func (w *StatsWriter) QueueFail(lost bool) {
+	atomic.AddUint64(&w.fail, 1)
+	if lost {
+		atomic.AddUint64(&w.lost, 1)
+	}
+}
+
+func (w *StatsWriter) QueueExec(spent time.Duration) {
+	w.mux.Lock()
+	w.exec.observe(w.bkt, spent)
+	w.mux.Unlock()
+}
+
+func (w *StatsWriter) QueueWait(dur time.Duration) {
+	w.mux.Lock()
+	w.qwait.observe(w.bkt, dur)
+	w.mux.Unlock()
+}
+
+func (w *StatsWriter) QueueSchedule(schedID int) {
+	atomic.StoreInt64(&w.sched, int64(schedID))
+}
+
+func (w *StatsWriter) QueueBreaker(state string) {
+	w.mux.Lock()
+	w.breaker[state]++
+	w.mux.Unlock()
+}
+
+func (w *StatsWriter) OverflowPut() {
+	atomic.AddUint64(&w.ovIn, 1)
+	atomic.AddInt64(&w.ovSize, 1)
+	atomic.AddInt64(&w.size, -1)
+}
+
+func (w *StatsWriter) OverflowPull() {
+	atomic.AddUint64(&w.ovOut, 1)
+	atomic.AddInt64(&w.ovSize, -1)
+	atomic.AddInt64(&w.size, 1)
+}
+
+func (w *StatsWriter) SubqPut(subq string) {
+	w.mux.Lock()
+	s := w.getSubq(subq)
+	s.In++
+	s.Size++
+	w.mux.Unlock()
+}
+
+func (w *StatsWriter) SubqPull(subq string) {
+	w.mux.Lock()
+	s := w.getSubq(subq)
+	s.Out++
+	s.Size--
+	w.mux.Unlock()
+}
+
+func (w *StatsWriter) SubqLeak(subq string) {
+	w.mux.Lock()
+	s := w.getSubq(subq)
+	s.Leak++
+	s.Size--
+	w.mux.Unlock()
+}
+
+func (w *StatsWriter) SubqWait(subq string, dur time.Duration) {
+	w.mux.Lock()
+	h, ok := w.swait[subq]
+	if !ok {
+		h = &histogram{cnt: make([]uint64, len(w.bkt)+1)}
+		w.swait[subq] = h
+	}
+	h.observe(w.bkt, dur)
+	w.mux.Unlock()
+}
+
+// Caution! Must be called under mutex.
+func (w *StatsWriter) getSubq(subq string) *SubqStats {
+	s, ok := w.subq[subq]
+	if !ok {
+		s = &SubqStats{}
+		w.subq[subq] = s
+	}
+	return s
+}
+
+func (h *histogram) observe(bkt []time.Duration, d time.Duration) {
+	if h.count == 0 || d < h.min {
+		h.min = d
+	}
+	if d > h.max {
+		h.max = d
+	}
+	h.count++
+	h.sum += d
+	h.cnt[sort.Search(len(bkt), func(i int) bool { return bkt[i] >= d })]++
+}
+
+func (h *histogram) snapshot(bkt []time.Duration) Histogram {
+	r := Histogram{Count: h.count, Sum: h.sum, Min: h.min, Max: h.max, Buckets: make([]HistogramBucket, len(h.cnt))}
+	for i := 0; i < len(h.cnt); i++ {
+		if i < len(bkt) {
+			r.Buckets[i].Le = bkt[i]
+		}
+		r.Buckets[i].Count = h.cnt[i]
+	}
+	return r
+}
+
+// Mean returns average value.
+func (h Histogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Count)
+}
+
+// Quantile returns upper bound of the bucket that contains q-quantile (q in range [0..1]).
+// Returns Max if quantile falls to the last (infinite) bucket.
+func (h Histogram) Quantile(q float64) time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	rank := uint64(q*float64(h.Count) + .5)
+	if rank == 0 {
+		rank = 1
+	}
+	var c uint64
+	for i := 0; i < len(h.Buckets); i++ {
+		if c += h.Buckets[i].Count; c >= rank {
+			if h.Buckets[i].Le == 0 || h.Buckets[i].Le > h.Max {
+				return h.Max
+			}
+			return h.Buckets[i].Le
+		}
+	}
+	return h.Max
+}
+
+var _ MetricsWriter = (*StatsWriter)(nil)
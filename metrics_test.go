@@ -0,0 +1,144 @@
+package queue
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStatsWriter(t *testing.T) {
+	t.Run("multi", func(t *testing.T) {
+		sw1, sw2 := NewStatsWriter(), NewStatsWriter()
+		q, err := New(&Config{
+			Capacity:      10,
+			Workers:       2,
+			Worker:        nopWorker{},
+			MetricsWriter: MultiWriter{sw1, sw2},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 5; i++ {
+			_ = q.Enqueue(i)
+		}
+		_ = q.Close()
+		for i := 0; i < 100 && sw1.Stats().Exec.Count < 5; i++ {
+			time.Sleep(time.Millisecond)
+		}
+		for _, sw := range []*StatsWriter{sw1, sw2} {
+			s := sw.Stats()
+			if s.In != 5 || s.Out != 5 || s.Size != 0 || s.Exec.Count != 5 || s.Schedule != -1 {
+				t.Errorf("stats mismatch: %+v", s)
+			}
+		}
+	})
+	t.Run("histogram", func(t *testing.T) {
+		sw := NewStatsWriter(time.Millisecond, time.Millisecond*10, time.Millisecond*100)
+		for i := 1; i <= 100; i++ {
+			sw.QueueExec(time.Millisecond * time.Duration(i))
+		}
+		sw.QueueExec(time.Second)
+		h := sw.Stats().Exec
+		if h.Count != 101 || h.Min != time.Millisecond || h.Max != time.Second {
+			t.Errorf("histogram mismatch: %+v", h)
+		}
+		exp := []uint64{1, 9, 90, 1}
+		for i, b := range h.Buckets {
+			if b.Count != exp[i] {
+				t.Errorf("bucket #%d mismatch: need %d, got %d", i, exp[i], b.Count)
+			}
+		}
+		if q := h.Quantile(.5); q != time.Millisecond*100 {
+			t.Errorf("p50 mismatch: %s", q)
+		}
+		if q := h.Quantile(1); q != time.Second {
+			t.Errorf("p100 mismatch: %s", q)
+		}
+		sw.Reset()
+		if h = sw.Stats().Exec; h.Count != 0 {
+			t.Error("reset failed")
+		}
+	})
+	t.Run("subq", func(t *testing.T) {
+		sw := NewStatsWriter()
+		sw.SubqPut("high")
+		sw.SubqPut("high")
+		sw.SubqPull("high")
+		sw.SubqLeak("low")
+		s := sw.Stats()
+		if s.Subq["high"] != (SubqStats{Size: 1, In: 2, Out: 1}) || s.Subq["low"] != (SubqStats{Size: -1, Leak: 1}) {
+			t.Errorf("subq stats mismatch: %+v", s.Subq)
+		}
+	})
+	t.Run("wait time", func(t *testing.T) {
+		clk := newTestClock(time.Now())
+		sw := NewStatsWriter()
+		w := &blockWorker{in: make(chan struct{}), out: make(chan struct{})}
+		q, err := New(&Config{
+			Capacity:      10,
+			Workers:       1,
+			Worker:        w,
+			MetricsWriter: sw,
+			Clock:         clk,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = q.Enqueue(1)
+		_ = q.Enqueue(2)
+		<-w.in // first item in progress
+		clk.Add(time.Second * 5)
+		w.out <- struct{}{}
+		<-w.in // second item in progress
+		w.out <- struct{}{}
+		_ = q.Close()
+		h := sw.Stats().QueueWait
+		if h.Count != 2 || h.Min != 0 || h.Max != time.Second*5 {
+			t.Errorf("wait time mismatch: %+v", h)
+		}
+	})
+	t.Run("unregister", func(t *testing.T) {
+		mw := &unregWriter{}
+		q, err := New(&Config{
+			Capacity:          10,
+			Workers:           1,
+			WorkersMin:        1,
+			WorkersMax:        2,
+			HeartbeatInterval: time.Millisecond * 10,
+			Worker:            nopWorker{},
+			MetricsWriter:     MultiWriter{mw, &DummyMetrics{}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = q.Close()
+		for i := 0; i < 100 && atomic.LoadInt32(&mw.n) == 0; i++ {
+			time.Sleep(time.Millisecond * 5)
+		}
+		if n := atomic.LoadInt32(&mw.n); n != 1 {
+			t.Errorf("unregister calls mismatch: need 1, got %d", n)
+		}
+		if n := atomic.LoadInt32(&q.workersRun); n != 0 {
+			t.Errorf("unregister before workers stop: %d running", n)
+		}
+	})
+}
+
+type unregWriter struct {
+	DummyMetrics
+	n int32
+}
+
+func (w *unregWriter) Unregister() {
+	atomic.AddInt32(&w.n, 1)
+}
+
+type blockWorker struct {
+	in, out chan struct{}
+}
+
+func (w *blockWorker) Do(_ any) error {
+	w.in <- struct{}{}
+	<-w.out
+	return nil
+}
@@ -0,0 +1,198 @@
+package queue
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Observer receives lifecycle events of the queue.
+//
+// Events deliver asynchronously by separate goroutine, so slow observer doesn't block workers and enqueue callers.
+// Events that don't fit the buffer (see Config.ObserverBuffer) drop, see Queue.ObserverDropped.
+type Observer interface {
+	Observe(e Event)
+}
+
+// ObserverFunc is an adapter to use ordinary functions as observers.
+type ObserverFunc func(e Event)
+
+func (fn ObserverFunc) Observe(e Event) { fn(e) }
+
+type EventType uint8
+
+const (
+	// EventLeak indicates that item leaked from full queue (to DLQ).
+	EventLeak EventType = iota
+	// EventFail indicates that item processing failed after all retries.
+	EventFail
+	// EventDeadline indicates that item dropped due to deadline.
+	EventDeadline
+	// EventWorker indicates worker status change.
+	EventWorker
+	// EventSchedule indicates schedule rule switch.
+	EventSchedule
+	// EventThrottle indicates throttle start/stop.
+	EventThrottle
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventLeak:
+		return "leak"
+	case EventFail:
+		return "fail"
+	case EventDeadline:
+		return "deadline"
+	case EventWorker:
+		return "worker"
+	case EventSchedule:
+		return "schedule"
+	case EventThrottle:
+		return "throttle"
+	}
+	return "unknown"
+}
+
+// Event is a common interface of all events.
+// Use type switch to get event details.
+type Event interface {
+	Type() EventType
+}
+
+// LeakEvent describes item leaked from full queue.
+type LeakEvent struct {
+	Time      time.Time
+	Direction LeakDirection
+	Payload   any
+	// DLQ enqueue error.
+	Err error
+}
+
+// FailEvent describes item that failed processing after all retries.
+type FailEvent struct {
+	Time    time.Time
+	Payload any
+	Retries uint32
+	// Processing error.
+	Err error
+	// Item sent to DLQ flag and DLQ enqueue error.
+	DLQ    bool
+	DLQErr error
+}
+
+// DeadlineEvent describes item dropped due to deadline.
+type DeadlineEvent struct {
+	Time    time.Time
+	Payload any
+	// Item sent to DLQ flag and DLQ enqueue error.
+	DLQ    bool
+	DLQErr error
+}
+
+// WorkerEvent describes worker status change.
+type WorkerEvent struct {
+	Time     time.Time
+	Worker   uint32
+	From, To WorkerStatus
+	Force    bool
+}
+
+// ScheduleEvent describes schedule rule switch.
+type ScheduleEvent struct {
+	Time                   time.Time
+	From, To               int
+	WorkersMin, WorkersMax uint32
+}
+
+// ThrottleEvent describes throttle start (Active is true) or stop.
+type ThrottleEvent struct {
+	Time   time.Time
+	Active bool
+	// Throttle caused by circuit breaker instead of queue fullness.
+	Breaker bool
+}
+
+func (*LeakEvent) Type() EventType     { return EventLeak }
+func (*FailEvent) Type() EventType     { return EventFail }
+func (*DeadlineEvent) Type() EventType { return EventDeadline }
+func (*WorkerEvent) Type() EventType   { return EventWorker }
+func (*ScheduleEvent) Type() EventType { return EventSchedule }
+func (*ThrottleEvent) Type() EventType { return EventThrottle }
+
+const defaultObserverBuffer = 1024
+
+// Internal events dispatcher.
+type observer struct {
+	o    Observer
+	ch   chan Event
+	drop uint64
+}
+
+func newObserver(o Observer, size uint) *observer {
+	if size == 0 {
+		size = defaultObserverBuffer
+	}
+	return &observer{o: o, ch: make(chan Event, size)}
+}
+
+// Put event to the buffer without blocking.
+func (o *observer) notify(e Event) {
+	select {
+	case o.ch <- e:
+	default:
+		atomic.AddUint64(&o.drop, 1)
+	}
+}
+
+// Deliver events to observer until queue is closed, empty and all workers are stopped.
+func (o *observer) run(q *Queue) {
+	ticker := time.NewTicker(q.c().HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case e := <-o.ch:
+			o.o.Observe(e)
+		case <-ticker.C:
+			if len(o.ch) == 0 && q.done() {
+				return
+			}
+		}
+	}
+}
+
+// ObserverDropped returns the number of events dropped due to observer's buffer overflow.
+func (q *Queue) ObserverDropped() uint64 {
+	if q.obs == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&q.obs.drop)
+}
+
+// Check if queue is closed, empty and all workers are stopped.
+func (q *Queue) done() bool {
+	if q.getStatus() != StatusClose || q.engine.size() > 0 {
+		return false
+	}
+	for i := 0; i < len(q.workers); i++ {
+		if q.workers[i].getStatus() != WorkerStatusIdle {
+			return false
+		}
+	}
+	return true
+}
+
+// Send leak event to observer.
+func (q *Queue) notifyLeak(dir LeakDirection, payload any, err error) {
+	if q.obs == nil {
+		return
+	}
+	q.obs.notify(&LeakEvent{Time: q.clk().Now(), Direction: dir, Payload: payload, Err: err})
+}
+
+// Send throttle start/stop event to observer.
+func (q *Queue) notifyThrottle(active, breaker bool) {
+	if q.obs == nil {
+		return
+	}
+	q.obs.notify(&ThrottleEvent{Time: q.clk().Now(), Active: active, Breaker: breaker})
+}
@@ -0,0 +1,112 @@
+package queue
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type failWorker struct{}
+
+func (failWorker) Do(_ any) error { return errors.New("fail") }
+
+// Wait for the first event matching given func.
+func awaitEvent(t *testing.T, ch chan Event, fn func(e Event) bool) {
+	timer := time.NewTimer(time.Second)
+	defer timer.Stop()
+	for {
+		select {
+		case e := <-ch:
+			if fn(e) {
+				return
+			}
+		case <-timer.C:
+			t.Fatal("event not found")
+		}
+	}
+}
+
+func TestObserver(t *testing.T) {
+	observe := func(ch chan Event) Observer {
+		return ObserverFunc(func(e Event) { ch <- e })
+	}
+	t.Run("leak", func(t *testing.T) {
+		ch := make(chan Event, 64)
+		w := &blockWorker{in: make(chan struct{}), out: make(chan struct{})}
+		q, err := New(&Config{
+			Capacity: 2,
+			Workers:  1,
+			Worker:   w,
+			DLQ:      DummyDLQ{},
+			Observer: observe(ch),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = q.Enqueue(0)
+		<-w.in
+		for i := 1; i <= 3; i++ {
+			_ = q.Enqueue(i)
+		}
+		awaitEvent(t, ch, func(e Event) bool {
+			le, ok := e.(*LeakEvent)
+			return ok && le.Payload == 3 && le.Direction == LeakDirectionRear && le.Err == nil
+		})
+		close(w.out)
+		go func() {
+			for range w.in {
+			}
+		}()
+		_ = q.Close()
+	})
+	t.Run("fail", func(t *testing.T) {
+		ch := make(chan Event, 64)
+		q, err := New(&Config{
+			Capacity:  10,
+			Workers:   1,
+			Worker:    failWorker{},
+			DLQ:       DummyDLQ{},
+			FailToDLQ: true,
+			Observer:  observe(ch),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = q.Enqueue("foo")
+		awaitEvent(t, ch, func(e Event) bool {
+			fe, ok := e.(*FailEvent)
+			return ok && fe.Payload == "foo" && fe.DLQ && fe.Err != nil
+		})
+		_ = q.Close()
+	})
+	t.Run("worker", func(t *testing.T) {
+		ch := make(chan Event, 64)
+		q, err := New(&Config{
+			Capacity: 10,
+			Workers:  1,
+			Worker:   nopWorker{},
+			Observer: observe(ch),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		awaitEvent(t, ch, func(e Event) bool {
+			we, ok := e.(*WorkerEvent)
+			return ok && we.Worker == 0 && we.From == WorkerStatusIdle && we.To == WorkerStatusActive
+		})
+		_ = q.Close()
+		awaitEvent(t, ch, func(e Event) bool {
+			we, ok := e.(*WorkerEvent)
+			return ok && we.To == WorkerStatusIdle
+		})
+	})
+	t.Run("drop", func(t *testing.T) {
+		o := newObserver(ObserverFunc(func(Event) {}), 1)
+		for i := 0; i < 3; i++ {
+			o.notify(&ThrottleEvent{Active: true})
+		}
+		if o.drop != 2 {
+			t.Errorf("dropped mismatch: need 2, got %d", o.drop)
+		}
+	})
+}
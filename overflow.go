@@ -0,0 +1,117 @@
+package queue
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// Default queue fullness rate to return items from overflow queue.
+	defaultOverflowThreshold = .5
+	// Default interval of overflow queue checks.
+	defaultOverflowInterval = time.Millisecond * 100
+)
+
+// Internal overflow mover.
+//
+// Moves items spilled to overflow queue back to the queue when queue fullness rate drops below
+// Config.OverflowThreshold. Items return in the order overflow queue gives them.
+type overflow struct {
+	oq OverflowQueue
+	// Item taken from overflow queue that doesn't fit the queue yet. Keeps items order.
+	pending *item
+	// Stop signal and stop confirmation.
+	sig, done chan struct{}
+	// Flag indicates that overflow queue gave back all items it should (see stop).
+	stopped uint32
+}
+
+func newOverflow(oq OverflowQueue) *overflow {
+	return &overflow{oq: oq, sig: make(chan struct{}), done: make(chan struct{})}
+}
+
+// Try to put item to overflow queue.
+// Please note, overflow queue keeps payload only, so returned item counts as new (see Queue.newItem).
+func (o *overflow) spill(q *Queue, itm *item) bool {
+	if err := o.oq.Enqueue(itm.payload); err != nil {
+		if l := q.l(); l != nil {
+			l.Debug("overflow enqueue failed", "error", err)
+		}
+		return false
+	}
+	q.mw().OverflowPut()
+	return true
+}
+
+// Check overflow queue periodically until stop signal.
+func (o *overflow) run(q *Queue) {
+	defer close(o.done)
+	ticker := time.NewTicker(q.c().OverflowInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-o.sig:
+			return
+		case <-ticker.C:
+			o.move(q)
+		}
+	}
+}
+
+// Move items from overflow queue to the queue while queue fullness rate is below the threshold.
+func (o *overflow) move(q *Queue) {
+	for q.Rate() < q.c().OverflowThreshold {
+		select {
+		case <-o.sig:
+			return
+		default:
+		}
+		if o.pending == nil {
+			x, ok := o.oq.Dequeue()
+			if !ok {
+				return
+			}
+			itm := q.newItem(x)
+			o.pending = &itm
+		}
+		if !q.engine.enqueue(o.pending, false) {
+			// Queue filled up concurrently, so keep the item till next check.
+			return
+		}
+		o.pending = nil
+		q.mw().OverflowPull()
+	}
+}
+
+// Stop the mover.
+// On graceful close all spilled items return to the queue (blocks till overflow queue is empty). On force close pending
+// item throws to DLQ and items remaining in overflow queue stay there.
+func (o *overflow) stop(q *Queue, force bool) {
+	defer atomic.StoreUint32(&o.stopped, 1)
+	close(o.sig)
+	<-o.done
+	for {
+		if o.pending == nil {
+			if force {
+				return
+			}
+			x, ok := o.oq.Dequeue()
+			if !ok {
+				return
+			}
+			itm := q.newItem(x)
+			o.pending = &itm
+		}
+		itm := o.pending
+		o.pending = nil
+		q.mw().OverflowPull()
+		if force || !q.engine.enqueue(itm, true) {
+			q.drop(itm)
+		}
+	}
+}
+
+// Check if overflow queue may give items to the queue yet.
+func (o *overflow) active() bool {
+	return o != nil && atomic.LoadUint32(&o.stopped) == 0
+}
@@ -0,0 +1,162 @@
+package queue
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Thread-safe FIFO overflow queue with optional size limit.
+type syncQueue struct {
+	mux   sync.Mutex
+	items []any
+	limit int
+}
+
+func (q *syncQueue) Enqueue(x any) error {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	if q.limit > 0 && len(q.items) >= q.limit {
+		return errors.New("overflow is full")
+	}
+	q.items = append(q.items, x)
+	return nil
+}
+
+func (q *syncQueue) Dequeue() (any, bool) {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	if len(q.items) == 0 {
+		return nil, false
+	}
+	x := q.items[0]
+	q.items = q.items[1:]
+	return x, true
+}
+
+func (q *syncQueue) len() int {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	return len(q.items)
+}
+
+// Worker that records processed items and blocks on the first one until gate opens.
+type gateWorker struct {
+	in, gate chan struct{}
+	mux      sync.Mutex
+	seen     []any
+}
+
+func (w *gateWorker) Do(x any) error {
+	w.mux.Lock()
+	w.seen = append(w.seen, x)
+	first := len(w.seen) == 1
+	w.mux.Unlock()
+	if first {
+		w.in <- struct{}{}
+	}
+	<-w.gate
+	return nil
+}
+
+func (w *gateWorker) processed() []any {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	return append([]any(nil), w.seen...)
+}
+
+func TestOverflow(t *testing.T) {
+	run := func(t *testing.T, oq *syncQueue, dlq envelopeDLQ) (*Queue, *gateWorker, *StatsWriter) {
+		w := &gateWorker{in: make(chan struct{}), gate: make(chan struct{})}
+		sw := NewStatsWriter()
+		q, err := New(&Config{
+			Capacity:         2,
+			Workers:          1,
+			Worker:           w,
+			DLQ:              dlq,
+			Overflow:         oq,
+			OverflowInterval: time.Millisecond,
+			MetricsWriter:    sw,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = q.Enqueue(0)
+		<-w.in
+		for i := 1; i <= 6; i++ {
+			_ = q.Enqueue(i)
+		}
+		return q, w, sw
+	}
+	t.Run("spill", func(t *testing.T) {
+		oq, dlq := &syncQueue{}, envelopeDLQ{ch: make(chan *DeadLetter, 64)}
+		q, w, sw := run(t, oq, dlq)
+		if n := oq.len(); n != 4 || len(dlq.ch) != 0 {
+			t.Fatalf("spill mismatch: overflow %d, DLQ %d", n, len(dlq.ch))
+		}
+		close(w.gate)
+		for i := 0; i < 1000 && len(w.processed()) < 7; i++ {
+			time.Sleep(time.Millisecond)
+		}
+		seen := w.processed()
+		if len(seen) != 7 {
+			t.Fatalf("processed %d items, expected 7", len(seen))
+		}
+		for i := 0; i < len(seen); i++ {
+			if seen[i] != i {
+				t.Errorf("order mismatch: %v", seen)
+				break
+			}
+		}
+		if s := sw.Stats(); s.OverflowIn != 4 || s.OverflowOut != 4 || s.OverflowSize != 0 || s.Size != 0 {
+			t.Errorf("metrics mismatch: in %d, out %d, size %d/%d", s.OverflowIn, s.OverflowOut, s.OverflowSize, s.Size)
+		}
+		_ = q.Close()
+	})
+	t.Run("reject", func(t *testing.T) {
+		oq, dlq := &syncQueue{limit: 2}, envelopeDLQ{ch: make(chan *DeadLetter, 64)}
+		q, w, sw := run(t, oq, dlq)
+		for i := 5; i <= 6; i++ {
+			dl := awaitDeadLetter(t, dlq)
+			if dl.Payload != i || dl.Reason != DLQReasonLeak {
+				t.Errorf("dead letter mismatch: %+v", dl)
+			}
+		}
+		if s := sw.Stats(); s.OverflowIn != 2 || s.Leak["rear"] != 2 {
+			t.Errorf("metrics mismatch: overflow in %d, leak %v", s.OverflowIn, s.Leak)
+		}
+		close(w.gate)
+		_ = q.Close()
+	})
+	t.Run("close", func(t *testing.T) {
+		oq, dlq := &syncQueue{}, envelopeDLQ{ch: make(chan *DeadLetter, 64)}
+		q, w, sw := run(t, oq, dlq)
+		close(w.gate)
+		_ = q.Close()
+		if n := oq.len(); n != 0 {
+			t.Errorf("overflow must be empty after close, got %d", n)
+		}
+		if err := q.Enqueue(7); err != ErrQueueClosed {
+			t.Errorf("unexpected error: %v", err)
+		}
+		for i := 0; i < 1000 && len(w.processed()) < 7; i++ {
+			time.Sleep(time.Millisecond)
+		}
+		if n := len(w.processed()); n != 7 || len(dlq.ch) != 0 {
+			t.Errorf("processed %d items, DLQ %d, expected 7 and 0", n, len(dlq.ch))
+		}
+		if s := sw.Stats(); s.OverflowOut != 4 || s.OverflowSize != 0 {
+			t.Errorf("metrics mismatch: out %d, size %d", s.OverflowOut, s.OverflowSize)
+		}
+	})
+	t.Run("force close", func(t *testing.T) {
+		oq, dlq := &syncQueue{}, envelopeDLQ{ch: make(chan *DeadLetter, 64)}
+		q, w, _ := run(t, oq, dlq)
+		_ = q.ForceClose()
+		close(w.gate)
+		if n := oq.len(); n != 4 {
+			t.Errorf("overflow must keep items after force close, got %d", n)
+		}
+	})
+}
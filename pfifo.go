@@ -44,10 +44,26 @@ func (e *pfifo) dequeue() (item, bool) {
 	return itm, ok
 }
 
-func (e *pfifo) dequeueSQ(_ uint32) (item, bool) {
+func (e *pfifo) classify(_ *item) {}
+
+func (e *pfifo) evict(_ uint32) (item, bool) {
 	return e.dequeue()
 }
 
+func (e *pfifo) tryDequeue() (item, bool) {
+	for i := uint64(0); i < e.m; i++ {
+		idx := atomic.AddUint64(&e.o, 1) % e.m
+		select {
+		case itm, ok := <-e.pool[idx]:
+			if ok {
+				return itm, true
+			}
+		default:
+		}
+	}
+	return item{}, false
+}
+
 func (e *pfifo) size() (r int) {
 	for i := uint64(0); i < e.m; i++ {
 		r += len(e.pool[i])
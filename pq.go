@@ -13,10 +13,12 @@ import (
 // PQ (priority queuing) engine implementation.
 type pq struct {
 	subq    []chan item // sub-queues list
+	tb      []*tbucket  // sub-queues rate limiters
 	egress  egress      // egress sub-queues
 	inprior [100]uint32 // ingress priority table
 	eprior  [100]uint32 // egress priority table (only for weighted algorithms)
 	conf    *Config     // main config instance
+	log     StructuredLogger
 	cancel  context.CancelFunc
 
 	ew  int32         // active egress workers
@@ -24,6 +26,7 @@ type pq struct {
 	ewc chan struct{} // egress workers control
 	ia  int64         // idle attempts
 	cp  uint64        // summing capacity
+	sb  chan struct{} // shared sub-queues buffer semaphore (priority eviction only)
 	ql  uint64        // sub-queues length
 	rri uint64        // RR/WRR counter
 }
@@ -41,9 +44,29 @@ func (e *pq) init(config *Config) error {
 	// Priorities tables calculation.
 	e.rebalancePT()
 
-	// Create channels.
+	// Sub-queues share summing capacity in priority eviction mode.
+	if q.Eviction == qos.EvictionPriority {
+		e.sb = make(chan struct{}, e.cp-q.Egress.Capacity)
+	}
+
+	// Create channels and rate limiters.
 	for i := 0; i < len(q.Queues); i++ {
-		e.subq = append(e.subq, make(chan item, q.Queues[i].Capacity))
+		q1 := &q.Queues[i]
+		cap_ := q1.Capacity
+		if e.sb != nil {
+			// Sub-queue may borrow space of lower priority sub-queues freed by eviction.
+			for j := i + 1; j < len(q.Queues); j++ {
+				if !q.Queues[j].Protected {
+					cap_ += q.Queues[j].Capacity
+				}
+			}
+		}
+		e.subq = append(e.subq, make(chan item, cap_))
+		var tb *tbucket
+		if q1.RateLimit > 0 {
+			tb = newTBucket(q1.RateLimit, q1.RateInterval, q1.RateBurst, config.Clock)
+		}
+		e.tb = append(e.tb, tb)
 	}
 	if err := e.egress.init(&config.QoS.Egress); err != nil {
 		return err
@@ -91,19 +114,20 @@ func (e *pq) init(config *Config) error {
 }
 
 func (e *pq) enqueue(itm *item, block bool) bool {
-	// Evaluate priority.
-	pp := e.qos().Evaluator.Eval(itm.payload)
-	if pp == 0 {
-		pp = 1
-	}
-	if pp > 100 {
-		pp = 100
+	if !itm.subqok {
+		e.classify(itm)
 	}
-	// Mark item with sub-queue index.
-	itm.subqi = atomic.LoadUint32(&e.inprior[pp-1])
 	q := e.subq[itm.subqi]
 	qn := e.qn(itm.subqi)
 	e.mw().SubqPut(qn)
+	if !e.reserve(block) {
+		// Shared buffer is full.
+		e.mw().SubqLeak(qn)
+		if e.log != nil {
+			e.log.Debug("sub-queue leak", logSubq, qn)
+		}
+		return false
+	}
 	if !block {
 		// Try to put item to the sub-queue in non-blocking mode.
 		select {
@@ -111,7 +135,11 @@ func (e *pq) enqueue(itm *item, block bool) bool {
 			e.tryUnlockEW()
 			return true
 		default:
+			e.release()
 			e.mw().SubqLeak(qn)
+			if e.log != nil {
+				e.log.Debug("sub-queue leak", logSubq, qn)
+			}
 			return false
 		}
 	} else {
@@ -123,6 +151,19 @@ func (e *pq) enqueue(itm *item, block bool) bool {
 	return true
 }
 
+// Evaluate priority and mark item with sub-queue index.
+func (e *pq) classify(itm *item) {
+	pp := e.qos().Evaluator.Eval(itm.payload)
+	if pp == 0 {
+		pp = 1
+	}
+	if pp > 100 {
+		pp = 100
+	}
+	itm.subqi = atomic.LoadUint32(&e.inprior[pp-1])
+	itm.subqok = true
+}
+
 // Try to send unlock signal to all active EW.
 func (e *pq) tryUnlockEW() {
 	atomic.StoreInt64(&e.ia, 0)
@@ -146,7 +187,35 @@ func (e *pq) dequeue() (item, bool) {
 	return itm, ok
 }
 
-func (e *pq) dequeueSQ(subqi uint32) (item, bool) {
+// Take item to leak considering eviction policy and protected sub-queues.
+func (e *pq) evict(subqi uint32) (item, bool) {
+	q := e.qos()
+	if e.sb != nil {
+		// Check sub-queues from the lowest priority to sub-queue of incoming item. Full sub-queue has no space to borrow,
+		// so only its own items may leak.
+		lo := len(e.subq) - 1
+		if sq := e.subq[subqi]; len(sq) == cap(sq) {
+			lo = int(subqi)
+		}
+		for i := lo; i >= int(subqi); i-- {
+			if q.Queues[i].Protected {
+				continue
+			}
+			select {
+			case itm, ok := <-e.subq[i]:
+				if ok {
+					e.release()
+					e.mw().SubqPull(e.qn(uint32(i)))
+					return itm, true
+				}
+			default:
+			}
+		}
+		return item{}, false
+	}
+	if q.Queues[subqi].Protected {
+		return item{}, false
+	}
 	itm, ok := <-e.subq[subqi]
 	if ok {
 		e.mw().SubqPull(e.qn(subqi))
@@ -154,6 +223,50 @@ func (e *pq) dequeueSQ(subqi uint32) (item, bool) {
 	return itm, ok
 }
 
+// Try to reserve space in shared buffer (if enabled). Waits for free space in blocking mode.
+func (e *pq) reserve(block bool) bool {
+	if e.sb == nil {
+		return true
+	}
+	if block {
+		e.sb <- struct{}{}
+		return true
+	}
+	select {
+	case e.sb <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Release space in shared buffer (if enabled).
+func (e *pq) release() {
+	if e.sb != nil {
+		<-e.sb
+	}
+}
+
+// Check egress first and sub-queues then.
+func (e *pq) tryDequeue() (item, bool) {
+	if itm, eqi, ok := e.egress.tryDequeue(); ok {
+		e.mw().SubqPull(e.egress.qn(eqi))
+		return itm, true
+	}
+	for i := 0; i < len(e.subq); i++ {
+		select {
+		case itm, ok := <-e.subq[i]:
+			if ok {
+				e.release()
+				e.mw().SubqPull(e.qn(uint32(i)))
+				return itm, true
+			}
+		default:
+		}
+	}
+	return item{}, false
+}
+
 func (e *pq) size() (sz int) {
 	return e.size1(true)
 }
@@ -251,16 +364,8 @@ exit:
 // it to egress.
 func (e *pq) shiftPQ() bool {
 	for i := 0; i < len(e.subq); i++ {
-		select {
-		case itm, ok := <-e.subq[i]:
-			if ok {
-				e.mw().SubqPull(e.qn(uint32(i)))
-				eqi := e.egress.enqueue(itm)
-				e.mw().SubqPut(e.egress.qn(eqi))
-				return true
-			}
-		default:
-			continue
+		if e.shift(uint32(i)) {
+			return true
 		}
 	}
 	return false
@@ -269,35 +374,41 @@ func (e *pq) shiftPQ() bool {
 // RR algorithm implementation: try to recv one single item from sequential sub-queue and send it to egress.
 func (e *pq) shiftRR() bool {
 	qi := atomic.AddUint64(&e.rri, 1) % e.ql // sub-queue index trick.
-	select {
-	case itm, ok := <-e.subq[qi]:
-		if ok {
-			e.mw().SubqPull(e.qn(uint32(qi)))
-			eqi := e.egress.enqueue(itm)
-			e.mw().SubqPut(e.egress.qn(eqi))
-			return true
-		}
-	default:
-		return false
-	}
-	return false
+	return e.shift(uint32(qi))
 }
 
 // WRR/DWRR algorithm implementation: try to recv one single item from sequential sub-queue (considering weight) and
 // send it to egress.
 func (e *pq) shiftWRR() bool {
 	pi := atomic.AddUint64(&e.rri, 1) % 100 // PT weight trick.
-	qi := e.eprior[pi]
+	return e.shift(e.eprior[pi])
+}
+
+// Try to recv one single item from sub-queue considering its rate limit and send it to egress.
+func (e *pq) shift(qi uint32) bool {
+	tb := e.tb[qi]
+	if tb != nil && !tb.take() {
+		// Sub-queue quota exceeded.
+		return false
+	}
 	select {
 	case itm, ok := <-e.subq[qi]:
 		if ok {
+			e.release()
 			e.mw().SubqPull(e.qn(qi))
+			if itm.enqueued > 0 {
+				e.mw().SubqWait(e.qn(qi), time.Duration(e.conf.Clock.Now().UnixNano()-itm.enqueued))
+			}
 			eqi := e.egress.enqueue(itm)
 			e.mw().SubqPut(e.egress.qn(eqi))
 			return true
 		}
 	default:
-		return false
+		break
+	}
+	if tb != nil {
+		// Nothing received, so return token back.
+		tb.refund()
 	}
 	return false
 }
@@ -365,6 +476,20 @@ func (e *egress) dequeue() (item, uint64, bool) {
 	return item{}, 0, false
 }
 
+func (e *egress) tryDequeue() (item, uint64, bool) {
+	for i := 0; i < len(e.pool); i++ {
+		idx := atomic.AddUint64(&e.o, 1) % e.m
+		select {
+		case itm, ok := <-e.pool[idx]:
+			if ok {
+				return itm, idx, true
+			}
+		default:
+		}
+	}
+	return item{}, 0, false
+}
+
 func (e *egress) size() (sz int) {
 	for i := 0; i < len(e.pool); i++ {
 		sz += len(e.pool[i])
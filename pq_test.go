@@ -2,10 +2,16 @@ package queue
 
 import (
 	"testing"
+	"time"
 
 	"github.com/koykov/queue/qos"
 )
 
+// Evaluator that takes priority from payload.
+type payloadEvaluator struct{}
+
+func (payloadEvaluator) Eval(x any) uint { return uint(x.(int)) }
+
 func TestPQ(t *testing.T) {
 	t.Run("priority table", func(t *testing.T) {
 		expectIPT := [100]uint32{
@@ -36,4 +42,76 @@ func TestPQ(t *testing.T) {
 		}
 		_ = q.close(false)
 	})
+	t.Run("eviction", func(t *testing.T) {
+		const high, medium, low = 10, 50, 90
+		type step struct {
+			x       int
+			evicted int // 0 means no eviction
+			fail    bool
+		}
+		run := func(t *testing.T, eviction qos.Eviction, steps []step) {
+			// Rate limit locks items in sub-queues.
+			subq := func(name string, protected bool) qos.Queue {
+				return qos.Queue{Name: name, Capacity: 2, Weight: 1, RateLimit: 1, RateInterval: time.Hour, Protected: protected}
+			}
+			conf := Config{
+				QoS: qos.New(qos.PQ, payloadEvaluator{}).SetEviction(eviction).
+					AddQueue(subq("high", false)).
+					AddQueue(subq("medium", true)).
+					AddQueue(subq("low", false)),
+				MetricsWriter: DummyMetrics{},
+				Clock:         newTestClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)),
+			}
+			_ = conf.QoS.Validate()
+			e := pq{}
+			if err := e.init(&conf); err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < len(e.tb); i++ {
+				e.tb[i].take()
+			}
+			for i, st := range steps {
+				itm := item{payload: st.x}
+				if e.enqueue(&itm, false) {
+					if st.evicted != 0 || st.fail {
+						t.Errorf("step %d: item %d admitted without eviction", i, st.x)
+					}
+					continue
+				}
+				v, ok := e.evict(itm.subqi)
+				if ok == st.fail || (ok && v.payload != st.evicted) {
+					t.Errorf("step %d: eviction mismatch: need %d, got %v (%t)", i, st.evicted, v.payload, ok)
+					continue
+				}
+				if ok && !e.enqueue(&itm, false) {
+					t.Errorf("step %d: item %d not admitted after eviction", i, st.x)
+				}
+			}
+			for {
+				if _, ok := e.tryDequeue(); !ok {
+					break
+				}
+			}
+			_ = e.close(false)
+		}
+		t.Run("priority", func(t *testing.T) {
+			run(t, qos.EvictionPriority, []step{
+				{x: low + 1}, {x: low + 2}, {x: medium + 1}, {x: medium + 2}, {x: high + 1}, {x: high + 2},
+				{x: high + 3, evicted: low + 1},
+				{x: low + 3, evicted: low + 2},
+				{x: low + 4, evicted: low + 3},
+				{x: high + 4, evicted: low + 4},
+				{x: high + 5, evicted: high + 1},
+				{x: medium + 3, fail: true},
+			})
+		})
+		t.Run("subq", func(t *testing.T) {
+			run(t, qos.EvictionSubq, []step{
+				{x: low + 1}, {x: low + 2}, {x: medium + 1}, {x: medium + 2}, {x: high + 1}, {x: high + 2},
+				{x: high + 3, evicted: high + 1},
+				{x: low + 3, evicted: low + 1},
+				{x: medium + 3, fail: true},
+			})
+		})
+	})
 }
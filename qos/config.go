@@ -20,6 +20,21 @@ const (
 	Ingress = "ingress"
 	Egress  = "egress"
 )
+
+// Eviction represents policy of front leak (see queue.LeakDirectionFront) in prioritized queue.
+type Eviction uint8
+
+const (
+	// EvictionSubq leaks the oldest item of the same sub-queue as incoming item.
+	EvictionSubq Eviction = iota
+	// EvictionPriority leaks the oldest item of the lowest priority sub-queue (but not higher than sub-queue of incoming
+	// item) to admit incoming item. Sub-queues order in Queues list considers as priority order (first is the highest),
+	// so the policy is available only for PQ algorithm.
+	// Sub-queues share their summing capacity, i.e. each sub-queue may borrow space freed by lower priority unprotected
+	// sub-queues. Caution! Therefore, each sub-queue allocates buffer of own capacity plus capacities of lower priority
+	// unprotected sub-queues.
+	EvictionPriority
+)
 const (
 	defaultEgressCapacity      = uint64(64)
 	defaultEgressStreams       = uint32(1)
@@ -39,6 +54,9 @@ type Config struct {
 	// Sub-queues config.
 	// Mandatory param.
 	Queues []Queue
+	// Eviction policy of front leak.
+	// If this param omit EvictionSubq will use by default.
+	Eviction Eviction
 }
 
 type EgressConfig struct {
@@ -83,6 +101,11 @@ func (q *Config) SetEvaluator(eval PriorityEvaluator) *Config {
 	return q
 }
 
+func (q *Config) SetEviction(eviction Eviction) *Config {
+	q.Eviction = eviction
+	return q
+}
+
 func (q *Config) SetEgressCapacity(cap uint64) *Config {
 	q.Egress.Capacity = cap
 	return q
@@ -117,12 +140,15 @@ func (q *Config) AddQueue(subq Queue) *Config {
 }
 
 // Validate check QoS config and returns any error encountered.
-func (q *Config) Validate() error {
-	if q.Algo > WRR {
-		return ErrUnknownAlgo
-	}
-	if q.Evaluator == nil {
-		return ErrNoEvaluator
+// Caution! Validate sets default values of omitted params, use Check to inspect config without modification.
+func (q *Config) Validate() (err error) {
+	q.Check(func(field string, e error) {
+		if err == nil {
+			err = fmt.Errorf("%s: %w", field, e)
+		}
+	})
+	if err != nil {
+		return
 	}
 	if q.Egress.Capacity == 0 {
 		q.Egress.Capacity = defaultEgressCapacity
@@ -139,48 +165,75 @@ func (q *Config) Validate() error {
 	if q.Egress.IdleTimeout == 0 {
 		q.Egress.IdleTimeout = defaultEgressIdleTimeout
 	}
-	if len(q.Queues) == 0 {
-		return ErrNoQueues
+	for i := 0; i < len(q.Queues); i++ {
+		// Weight fills omitted ingress/egress weights.
+		q1 := &q.Queues[i]
+		if q1.IngressWeight == 0 {
+			q1.IngressWeight = q1.Weight
+		}
+		if q1.EgressWeight == 0 {
+			q1.EgressWeight = q1.Weight
+		}
+	}
+	return nil
+}
+
+// Check inspects QoS config without modification and reports every found problem to fn.
+// Param field contains path of the problem field, eg "Queues[1].Capacity".
+func (q *Config) Check(fn func(field string, err error)) {
+	if q.Algo > WRR {
+		fn("Algo", ErrUnknownAlgo)
 	}
-	if len(q.Queues) == 1 {
-		return ErrSenseless
+	if q.Evaluator == nil {
+		fn("Evaluator", ErrNoEvaluator)
+	}
+	if q.Eviction > EvictionPriority {
+		fn("Eviction", ErrUnknownEviction)
+	}
+	if q.Eviction == EvictionPriority && q.Algo != PQ {
+		fn("Eviction", ErrEvictionAlgo)
+	}
+	switch len(q.Queues) {
+	case 0:
+		fn("Queues", ErrNoQueues)
+	case 1:
+		fn("Queues", ErrSenseless)
 	}
 	for i := 0; i < len(q.Queues); i++ {
 		q1 := &q.Queues[i]
+		field := "Queues[" + strconv.Itoa(i) + "]"
 		if len(q1.Name) == 0 {
-			return fmt.Errorf("QoS: queue at index %d has no name", i)
+			fn(field+".Name", ErrNoName)
 		}
 		if q1.Name == Ingress || q1.Name == Egress {
-			return ErrNameReserved
+			fn(field+".Name", ErrNameReserved)
 		}
 		if q1.Capacity == 0 {
-			return fmt.Errorf("QoS: queue #%s has no capacity", q1.Name)
+			fn(field+".Capacity", ErrNoCapacity)
 		}
-
 		// Check weight config.
 		switch {
 		case q1.Weight == 0 && q1.IngressWeight == 0 && q1.EgressWeight == 0:
-			return fmt.Errorf("QoS: queue #%s is senseless due to no weight", q1.Name)
-		case q1.Weight != 0 && q1.IngressWeight != 0 && q1.EgressWeight != 0:
-			continue
-		case q1.Weight != 0 && q1.IngressWeight == 0 && q1.EgressWeight == 0:
-			q1.IngressWeight, q1.EgressWeight = q1.Weight, q1.Weight
-		case q1.Weight == 0 && q1.IngressWeight != 0 && q1.EgressWeight != 0:
-			continue
-		case q1.Weight == 0 && q1.IngressWeight == 0 && q1.EgressWeight != 0:
-			return fmt.Errorf("QoS: queue #%s has egress weight, but haven't ingress weight", q1.Name)
-		case q1.Weight == 0 && q1.IngressWeight != 0 && q1.EgressWeight == 0:
-			return fmt.Errorf("QoS: queue #%s has ingress weight, but haven't egress weight", q1.Name)
-		case q1.Weight != 0 && q1.IngressWeight == 0 && q1.EgressWeight != 0:
-			q1.IngressWeight = q1.Weight
-		case q1.Weight != 0 && q1.IngressWeight != 0 && q1.EgressWeight == 0:
-			q1.EgressWeight = q1.Weight
-		default:
-			return fmt.Errorf("QoS: queue #%s unconsidered weight config: weight %d, ingress %d, egress %d",
-				q1.Name, q1.Weight, q1.IngressWeight, q1.EgressWeight)
+			fn(field+".Weight", ErrNoWeight)
+		case q1.Weight == 0 && q1.IngressWeight == 0:
+			fn(field+".IngressWeight", ErrNoIngressWeight)
+		case q1.Weight == 0 && q1.EgressWeight == 0:
+			fn(field+".EgressWeight", ErrNoEgressWeight)
+		}
+		if dp := q1.DropProfile; dp != nil && !dp.valid() {
+			fn(field+".DropProfile", ErrBadDropProfile)
 		}
 	}
-	return nil
+}
+
+// HasQueue checks if sub-queue with given name exists.
+func (q *Config) HasQueue(name string) bool {
+	for i := 0; i < len(q.Queues); i++ {
+		if q.Queues[i].Name == name {
+			return true
+		}
+	}
+	return false
 }
 
 // SummingCapacity returns sum of capacities of all sub-queues (including egress).
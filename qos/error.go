@@ -3,10 +3,19 @@ package qos
 import "errors"
 
 var (
-	ErrNoConfig     = errors.New("no QoS config provided")
-	ErrUnknownAlgo  = errors.New("unknown QoS scheduling algorithm")
-	ErrNoEvaluator  = errors.New("no QoS priority evaluator provided")
-	ErrNoQueues     = errors.New("no QoS queues")
-	ErrSenseless    = errors.New("QoS is senseless")
-	ErrNameReserved = errors.New("names 'ingress' and 'egress' are reserved")
+	ErrNoConfig        = errors.New("no QoS config provided")
+	ErrUnknownAlgo     = errors.New("unknown QoS scheduling algorithm")
+	ErrNoEvaluator     = errors.New("no QoS priority evaluator provided")
+	ErrUnknownEviction = errors.New("unknown QoS eviction policy")
+	ErrEvictionAlgo    = errors.New("priority eviction is available only for PQ scheduling algorithm")
+	ErrNoQueues        = errors.New("no QoS queues")
+	ErrSenseless       = errors.New("QoS is senseless")
+	ErrNameReserved    = errors.New("names 'ingress' and 'egress' are reserved")
+
+	ErrNoName          = errors.New("sub-queue has no name")
+	ErrNoCapacity      = errors.New("sub-queue has no capacity")
+	ErrNoWeight        = errors.New("sub-queue is senseless due to no weight")
+	ErrNoIngressWeight = errors.New("sub-queue has egress weight, but haven't ingress weight")
+	ErrNoEgressWeight  = errors.New("sub-queue has ingress weight, but haven't egress weight")
+	ErrBadDropProfile  = errors.New("sub-queue drop profile thresholds must be in range 0..1 and min less than max")
 )
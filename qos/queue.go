@@ -1,5 +1,7 @@
 package qos
 
+import "time"
+
 // Queue represent QoS sub-queue config.
 type Queue struct {
 	// Name of sub-queue. Uses for metrics.
@@ -17,4 +19,38 @@ type Queue struct {
 	// Egress weight of sub-queue.
 	// Mandatory param if Weight omitted.
 	EgressWeight uint64
+	// RateLimit limits the number of items that may leave sub-queue per RateInterval.
+	// Each sub-queue has own quota, so one sub-queue cannot consume quota of another.
+	// Zero value disables rate limiting.
+	RateLimit uint64
+	// RateInterval is a period of RateLimit.
+	// If this param omit 1 second will use instead.
+	RateInterval time.Duration
+	// RateBurst indicates how many items may leave sub-queue at once above the RateLimit.
+	// If this param omit 1 will use instead.
+	RateBurst uint64
+	// Protected sub-queue never leaks buffered items to admit incoming ones (see Config.Eviction). Incoming items of
+	// full protected sub-queue leak instead (rear direction).
+	Protected bool
+	// DropProfile of the sub-queue for weighted RED policy (WRED, see aqm.RED).
+	// If this param omit drop profile of the policy will use instead.
+	DropProfile *DropProfile
+}
+
+// DropProfile describes early drop params of RED policy.
+//
+// Items don't drop while average queue fullness rate is less than MinThreshold. Between MinThreshold and MaxThreshold
+// drop probability grows linearly up to MaxProbability. All items drop when average rate exceeds MaxThreshold.
+// Zero fields mean corresponding params of the policy.
+type DropProfile struct {
+	MinThreshold, MaxThreshold float32
+	MaxProbability             float32
+}
+
+func (p *DropProfile) valid() bool {
+	in := func(x float32) bool { return x >= 0 && x <= 1 }
+	if !in(p.MinThreshold) || !in(p.MaxThreshold) || !in(p.MaxProbability) {
+		return false
+	}
+	return p.MinThreshold == 0 || p.MaxThreshold == 0 || p.MinThreshold < p.MaxThreshold
 }
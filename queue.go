@@ -1,7 +1,9 @@
 package queue
 
 import (
+	"context"
 	"encoding/json"
+	"math"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -20,6 +22,7 @@ const (
 
 	flagBalanced = 0
 	flagLeaky    = 1
+	flagAdmit    = 2
 )
 
 // Queue is an implementation of balanced leaky queue.
@@ -35,6 +38,11 @@ type Queue struct {
 	config *Config
 	// ID of actual schedule rule. Contains -1 by default (no rule found).
 	schedID int
+	// Actual (applied) realtime params.
+	rtp realtimeParams
+	// Ramp start bounds and timestamp. Zero timestamp means no ramp in progress.
+	rampFrom realtimeParams
+	rampTS   int64
 	// The number of maximum workers that queue may contain considering all schedule rules and config params.
 	wmax uint32
 
@@ -42,6 +50,15 @@ type Queue struct {
 	status Status
 	// Internal engine.
 	engine engine
+	// Circuit breaker (if enabled).
+	breaker *Breaker
+	// Dequeue rate limiter (if enabled).
+	tb *tbucket
+	// Realtime values of config params that schedule overlay may overwrite.
+	retryInterval, deadlineInterval int64
+	leakDirection                   uint32
+	// Initial weights (ingress, egress) of QoS sub-queues.
+	qosw [][2]uint64
 
 	mux sync.Mutex
 	// Workers pool.
@@ -51,12 +68,27 @@ type Queue struct {
 
 	// Counter of active workers.
 	workersUp int32
+	// Counter of running workers goroutines.
+	workersRun int32
+	// Heartbeat exit signal (balanced queue only).
+	hbDone chan struct{}
 	// Calibration lock counter.
 	c9nlock uint32
 	// Spinlock of queue.
 	spinlock int64
 	// Enqueue lock counter.
 	enqlock int64
+	// Structured logger (if enabled).
+	log StructuredLogger
+	// Events observer (if enabled).
+	obs *observer
+	// Overflow mover (if enabled).
+	ovf *overflow
+	// Stats since last calibration: enqueued, processed, failed items and total execution time.
+	enqN, execN, execFail uint64
+	execNs                int64
+	// Last calibration timestamp.
+	c9nTS int64
 
 	err error
 }
@@ -67,7 +99,13 @@ type item struct {
 	retries  uint32
 	delay    int64  // Delayed execution expire time (Unix ns timestamp).
 	deadline int64  // Deadline time (Unix ns timestamp).
+	enqueued int64  // Enqueue time, resets on retry (Unix ns timestamp).
+	created  int64  // Original enqueue time (Unix ns timestamp).
 	subqi    uint32 // Sub-queue index.
+	subqok   bool   // Sub-queue index is actual, so item doesn't need classification.
+	err      error  // Last processing error.
+	// Producer's context (if tracing enabled).
+	ctx context.Context
 }
 
 // realtimeParams describes queue params for current time.
@@ -118,6 +156,15 @@ func (q *Queue) init() {
 		c.MetricsWriter = DummyMetrics{}
 	}
 
+	if c.Balancer == nil {
+		c.Balancer = FullnessBalancer{}
+	}
+
+	q.log = newLogger(c)
+	if c.Observer != nil {
+		q.obs = newObserver(c.Observer, c.ObserverBuffer)
+	}
+
 	if c.Backoff == nil {
 		c.Backoff = DummyBackoff{}
 	}
@@ -170,7 +217,24 @@ func (q *Queue) init() {
 		c.HeartbeatInterval = defaultHeartbeatInterval
 	}
 
-	if c.LeakDirection == LeakDirectionFront && c.FrontLeakAttempts == 0 {
+	if c.DLQRetryTimeout > 0 && c.DLQRetryInterval == 0 {
+		c.DLQRetryInterval = defaultDLQRetryInterval
+	}
+
+	if c.Overflow != nil {
+		if c.OverflowThreshold <= 0 {
+			c.OverflowThreshold = defaultOverflowThreshold
+		}
+		if c.OverflowThreshold > defaultFactorLimit {
+			c.OverflowThreshold = defaultFactorLimit
+		}
+		if c.OverflowInterval == 0 {
+			c.OverflowInterval = defaultOverflowInterval
+		}
+	}
+
+	if c.FrontLeakAttempts == 0 {
+		// Schedule overlay may switch leak direction, so set attempts independent of direction.
 		c.FrontLeakAttempts = defaultFrontLeakAttempts
 	}
 
@@ -182,7 +246,7 @@ func (q *Queue) init() {
 			return
 		}
 		c.Capacity = c.QoS.SummingCapacity()
-		q.engine = &pq{}
+		q.engine = &pq{log: q.log}
 	case c.Streams > 0:
 		q.engine = &pfifo{}
 	default:
@@ -193,42 +257,68 @@ func (q *Queue) init() {
 		return
 	}
 
+	if c.RateLimit > 0 || (c.Schedule != nil && c.Schedule.hasRateOverlay()) {
+		q.tb = newTBucket(c.RateLimit, c.RateInterval, c.RateBurst, c.Clock)
+	}
+	if c.QoS != nil {
+		for i := 0; i < len(c.QoS.Queues); i++ {
+			q1 := &c.QoS.Queues[i]
+			q.qosw = append(q.qosw, [2]uint64{q1.IngressWeight, q1.EgressWeight})
+		}
+	}
+
+	if c.Breaker != nil {
+		c.Breaker.Clock = c.Clock
+		q.breaker = newBreaker(*c.Breaker, q.breakerNotify)
+	}
+
 	// Check flags.
 	q.SetBit(flagBalanced, c.WorkersMin < c.WorkersMax || c.Schedule != nil)
 	q.SetBit(flagLeaky, c.DLQ != nil)
+	q.SetBit(flagAdmit, c.AQM != nil && !aqmKeepOnly(c.AQM))
+
+	if c.Schedule != nil {
+		c.Schedule.SetClock(c.Clock)
+	}
 
 	// Check initial params.
+	q.c9nTS = c.Clock.Now().UnixNano()
 	q.wmax = q.workersMaxDaily()
 	var params realtimeParams
 	params, q.schedID = q.rtParams()
+	q.rtp = params
+	q.applyOverlay(q.schedOverlay(q.schedID))
+	q.mw().QueueSchedule(q.schedID)
 
 	// Make workers pool/
 	q.workers = make([]*worker, q.wmax)
 	var i uint32
 	for i = 0; i < q.wmax; i++ {
 		q.mw().WorkerSleep(i)
-		q.workers[i] = makeWorker(i, c)
+		q.workers[i] = makeWorker(i, c, q.log, q.obs)
 	}
 	q.mw().WorkerSetup(0, 0, uint(params.WorkersMax))
 
 	// Start [0...workersMin] workers.
 	for i = 0; i < params.WorkersMin; i++ {
 		q.workers[i].signal(sigInit)
-		go q.workers[i].await(q)
+		q.runWorker(q.workers[i])
 	}
 	q.workersUp = int32(params.WorkersMin)
 
 	if q.CheckBit(flagBalanced) {
 		// Init background heartbeat ticker.
 		tickerHB := time.NewTicker(c.HeartbeatInterval)
+		q.hbDone = make(chan struct{})
 		go func() {
 			for {
 				select {
 				case <-tickerHB.C:
 					// Calibrate queue on each tick in regular mode.
 					q.calibrate(false)
-					if q.Rate() == 0 && q.getStatus() == StatusClose {
+					if q.Rate() == 0 && q.getStatus() == StatusClose && !q.ovf.active() {
 						tickerHB.Stop()
+						close(q.hbDone)
 						// Exit on empty stopped queue.
 						return
 					}
@@ -237,12 +327,25 @@ func (q *Queue) init() {
 		}()
 	}
 
+	if q.obs != nil {
+		go q.obs.run(q)
+	}
+	if c.Overflow != nil && q.CheckBit(flagLeaky) {
+		q.ovf = newOverflow(c.Overflow)
+		go q.ovf.run(q)
+	}
+
 	// Queue is ready!
 	q.setStatus(StatusActive)
 }
 
 // Enqueue puts x to the queue.
 func (q *Queue) Enqueue(x any) error {
+	return q.EnqueueCtx(context.Background(), x)
+}
+
+// EnqueueCtx puts x to the queue and captures trace context of the producer from ctx (see Config.Tracer).
+func (q *Queue) EnqueueCtx(ctx context.Context, x any) error {
 	q.once.Do(q.init)
 	// Check if enqueue is possible.
 	if status := q.getStatus(); status == StatusClose || status == StatusFail {
@@ -259,64 +362,80 @@ func (q *Queue) Enqueue(x any) error {
 			q.calibrate(true)
 		}
 	}
-	// Prepare item.
-	itm := item{payload: x}
+	atomic.AddUint64(&q.enqN, 1)
+
+	itm := q.newItem(x)
+	q.traceEnqueue(ctx, &itm)
+
+	return q.renqueue(&itm)
+}
+
+// Prepare item considering delay and deadline params.
+func (q *Queue) newItem(x any) item {
+	now := q.clk().Now()
+	itm := item{payload: x, enqueued: now.UnixNano(), created: now.UnixNano()}
 	if di := q.c().DelayInterval; di > 0 {
-		itm.delay = q.clk().Now().Add(di).UnixNano()
+		itm.delay = now.Add(di).UnixNano()
 	}
-	if di := q.c().DeadlineInterval; di > 0 {
-		itm.deadline = q.clk().Now().Add(di).UnixNano()
+	if di := time.Duration(atomic.LoadInt64(&q.deadlineInterval)); di > 0 {
+		itm.deadline = now.Add(di).UnixNano()
 	}
 	switch x.(type) {
 	case Job:
 		job := x.(Job)
 		if job.DelayInterval > 0 {
-			itm.delay = q.clk().Now().Add(job.DelayInterval).UnixNano()
+			itm.delay = now.Add(job.DelayInterval).UnixNano()
 		}
 		if job.DeadlineInterval > 0 {
-			itm.deadline = q.clk().Now().Add(job.DeadlineInterval).UnixNano()
+			itm.deadline = now.Add(job.DeadlineInterval).UnixNano()
 		}
 	case *Job:
 		job := x.(*Job)
 		if job.DelayInterval > 0 {
-			itm.delay = q.clk().Now().Add(job.DelayInterval).UnixNano()
+			itm.delay = now.Add(job.DelayInterval).UnixNano()
 		}
 		if job.DeadlineInterval > 0 {
-			itm.deadline = q.clk().Now().Add(job.DeadlineInterval).UnixNano()
+			itm.deadline = now.Add(job.DeadlineInterval).UnixNano()
 		}
 	}
-
-	return q.renqueue(&itm)
+	return itm
 }
 
 // Put wrapped item to the queue.
 // This method also uses for enqueue retries (see Config.MaxRetries).
 func (q *Queue) renqueue(itm *item) (err error) {
 	q.mw().QueuePut()
+	// Classify item again on each (re)enqueue.
+	itm.subqok = false
 	if q.CheckBit(flagLeaky) {
+		// Check early drop by AQM policy.
+		if q.CheckBit(flagAdmit) && !q.c().AQM.Admit(q.aqmSnapshot(itm)) {
+			return q.leak(itm, LeakDirectionRear)
+		}
 		// Put item to the stream in leaky mode.
 		if !q.engine.enqueue(itm, false) {
+			// Try to spill the item to overflow queue.
+			if q.ovf != nil && q.ovf.spill(q, itm) {
+				return
+			}
 			// Leak the item to DLQ.
-			if q.c().LeakDirection == LeakDirectionFront {
+			if q.getLeakDirection() == LeakDirectionFront {
 				// Front direction, first need to extract item to leak from queue front.
 				for i := uint32(0); i < q.c().FrontLeakAttempts; i++ {
-					itmf, _ := q.engine.dequeueSQ(itm.subqi)
-					if err = q.c().DLQ.Enqueue(itmf.payload); err != nil {
-						q.mw().QueueLost()
-						return
+					itmf, ok := q.engine.evict(itm.subqi)
+					if !ok {
+						break
 					}
-					q.mw().QueueLeak(LeakDirectionFront.String())
+					// Front item lost on DLQ failure doesn't matter, incoming item still may take its place.
+					_ = q.leak(&itmf, LeakDirectionFront)
 					if q.engine.enqueue(itm, false) {
 						return
-					} else {
-						continue
 					}
 				}
 				// Front leak failed, fallback to rear direction.
 			}
 			// Rear direction, just leak item.
-			err = q.c().DLQ.Enqueue(itm.payload)
-			q.mw().QueueLeak(LeakDirectionRear.String())
+			err = q.leak(itm, LeakDirectionRear)
 		}
 	} else {
 		// Regular put (blocking mode).
@@ -325,6 +444,37 @@ func (q *Queue) renqueue(itm *item) (err error) {
 	return
 }
 
+// Leak item to DLQ in given direction.
+// Returns DLQ error if item finally lost.
+func (q *Queue) leak(itm *item, dir LeakDirection) error {
+	err := q.deliverDLQ(itm, DLQReasonLeak, dir, nil)
+	q.traceDLQ(itm.ctx, DLQReasonLeak, err)
+	q.notifyLeak(dir, itm.payload, err)
+	if err != nil {
+		q.mw().QueueLost()
+		return err
+	}
+	q.mw().QueueLeak(dir.String())
+	return nil
+}
+
+// Dequeue takes item from the queue in non-blocking mode. Returns false if queue is empty.
+//
+// Method allows to use the queue as a source of Redriver (eg: if queue uses as DLQ of other queue). Please note, workers
+// of the queue compete with the caller for items.
+func (q *Queue) Dequeue() (any, bool) {
+	q.once.Do(q.init)
+	if q.getStatus() == StatusFail {
+		return nil, false
+	}
+	itm, ok := q.engine.tryDequeue()
+	if !ok {
+		return nil, false
+	}
+	q.mw().QueuePull()
+	return itm.payload, true
+}
+
 // Size return actual size of the queue.
 func (q *Queue) Size() int {
 	return q.engine.size()
@@ -343,7 +493,7 @@ func (q *Queue) Rate() float32 {
 // Close gracefully stops the queue.
 //
 // After receiving of close signal at least workersMin number of workers will work so long as queue has items.
-// Enqueue of new items to queue will forbid.
+// Enqueue of new items to queue will forbid. Items spilled to overflow queue return to the queue before close.
 func (q *Queue) Close() error {
 	return q.close(false)
 }
@@ -359,18 +509,22 @@ func (q *Queue) close(force bool) error {
 	if q.getStatus() == StatusClose {
 		return ErrQueueClosed
 	}
-	if q.l() != nil {
+	if l := q.l(); l != nil {
 		msg := "caught close signal"
 		if force {
 			msg = "caught force close signal"
 		}
-		q.l().Printf(msg)
+		l.Info(msg, logRate, q.Rate())
 	}
 	// Set the status.
 	q.setStatus(StatusClose)
 	// Wait till all enqueue operations will finish.
 	for atomic.LoadInt64(&q.enqlock) > 0 {
 	}
+	if q.ovf != nil {
+		// Stop overflow mover before workers. Graceful close returns spilled items to the queue.
+		q.ovf.stop(q, force)
+	}
 
 	if force {
 		// Immediately stop all active/sleeping workers.
@@ -388,17 +542,52 @@ func (q *Queue) close(force bool) error {
 		// Throw all remaining items to DLQ or trash.
 		for q.engine.size() > 0 {
 			itm, _ := q.engine.dequeue()
-			if q.CheckBit(flagLeaky) {
-				_ = q.c().DLQ.Enqueue(itm.payload)
-				q.mw().QueueLeak(LeakDirectionFront.String())
-			} else {
-				q.mw().QueueLost()
-			}
+			q.drop(&itm)
 		}
 	}
 	// Close the stream.
 	// Please note, this is not the end for regular close case. Workers continue works while queue has items.
-	return q.engine.close(force)
+	err := q.engine.close(force)
+	if mu, ok := q.mw().(metricsUnregisterer); ok {
+		go q.unregister(mu)
+	}
+	return err
+}
+
+// Start processing goroutine of the worker.
+func (q *Queue) runWorker(w *worker) {
+	atomic.AddInt32(&q.workersRun, 1)
+	go func() {
+		defer atomic.AddInt32(&q.workersRun, -1)
+		w.await(q)
+	}()
+}
+
+// Wait till heartbeat and all workers stop writing metrics and unregister queue's metrics.
+func (q *Queue) unregister(mu metricsUnregisterer) {
+	if q.hbDone != nil {
+		<-q.hbDone
+	}
+	for atomic.LoadInt32(&q.workersRun) > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	mu.Unregister()
+}
+
+// Throw item to DLQ or trash on force close.
+func (q *Queue) drop(itm *item) {
+	if !q.CheckBit(flagLeaky) {
+		q.mw().QueueLost()
+		return
+	}
+	err := q.deliverDLQ(itm, DLQReasonClose, LeakDirectionFront, nil)
+	q.traceDLQ(itm.ctx, DLQReasonClose, err)
+	q.notifyLeak(LeakDirectionFront, itm.payload, err)
+	if err != nil {
+		q.mw().QueueLost()
+		return
+	}
+	q.mw().QueueLeak(LeakDirectionFront.String())
 }
 
 // Internal calibration helper.
@@ -423,140 +612,267 @@ func (q *Queue) calibrate(force bool) {
 	atomic.StoreInt64(&q.spinlock, 0)
 
 	rate := q.Rate()
-	if q.l() != nil {
-		msg := "calibrate: rate %f, workers %d"
+	if l := q.l(); l != nil {
+		msg := "calibrate"
 		if force {
-			msg = "force calibrate: rate %f, workers %d"
+			msg = "force calibrate"
 		}
-		q.l().Printf(msg, rate, atomic.LoadInt32(&q.workersUp))
+		l.Debug(msg, logRate, rate, "workers", atomic.LoadInt32(&q.workersUp), logSchedID, q.schedID)
 	}
 
 	// Check and stop pre-sleeping workers.
-	for i := q.c().WorkersMax - 1; i >= q.c().WorkersMin; i-- {
+	q.stopSlept()
+	// Check schedID change.
+	params := q.switchSched()
+	// Check close and throttle statuses.
+	if !q.checkStatus(rate, params) {
+		return
+	}
+	// Balance active workers.
+	q.balance(rate, params)
+}
+
+// Stop workers that slept enough time.
+func (q *Queue) stopSlept() {
+	for i := int(q.wmax) - 1; i >= 0; i-- {
 		if q.workers[i].getStatus() == WorkerStatusSleep && q.workers[i].sleptEnough() {
 			q.workers[i].signal(sigStop)
 		}
 	}
+}
 
-	// Check schedID change.
-	var (
-		params  realtimeParams
-		schedID int
-	)
-	if params, schedID = q.rtParams(); schedID != q.schedID {
+// Check schedule rule change and apply new rule params.
+// Returns actual realtime params.
+func (q *Queue) switchSched() realtimeParams {
+	params, schedID := q.rtParams()
+	if schedID != q.schedID {
+		if q.obs != nil {
+			q.obs.notify(&ScheduleEvent{Time: q.clk().Now(), From: q.schedID, To: schedID,
+				WorkersMin: params.WorkersMin, WorkersMax: params.WorkersMax})
+		}
 		q.schedID = schedID
-		if q.l() != nil {
-			q.l().Printf("switch to schedID %d (workers %d/%d, wakeup factor %f, sleep factor %f)",
-				schedID, params.WorkersMin, params.WorkersMax, params.WakeupFactor, params.SleepFactor)
-		}
-		// Stop all workers in range [workersMax...wmax].
-		// wmax is a number of maximum workers queue may have.
-		// workersMax is a maximum number of workers queue may have in current time range.
-		if q.wmax > params.WorkersMax {
-			for i := q.wmax - 1; i >= params.WorkersMax; i-- {
-				if q.workers[i].getStatus() == WorkerStatusActive {
-					q.workers[i].stop(true)
-					atomic.AddInt32(&q.workersUp, -1)
-				}
-			}
+		if l := q.l(); l != nil {
+			l.Info("switch schedule rule", logSchedID, schedID, "workers_min", params.WorkersMin,
+				"workers_max", params.WorkersMax, "wakeup_factor", params.WakeupFactor, "sleep_factor", params.SleepFactor)
 		}
-		// Check new params.WorkersMin exceeds number of active workers.
-		if wu := uint32(q.getWorkersUp()); params.WorkersMin > wu {
-			// Start params.WorkersMin-workersUp workers to satisfy queue.
-			target := params.WorkersMin - wu
-			var c uint32
-			for i := uint32(0); i < q.wmax; i++ {
-				switch q.workers[i].getStatus() {
-				case WorkerStatusIdle:
-					q.workers[i].signal(sigInit)
-					go q.workers[i].await(q)
-				case WorkerStatusSleep:
-					q.workers[i].signal(sigWakeup)
-				default:
-					continue
-				}
-				c++
-				atomic.AddInt32(&q.workersUp, 1)
-				if c == target {
-					break
-				}
-			}
+		q.applyOverlay(q.schedOverlay(schedID))
+		q.mw().QueueSchedule(schedID)
+		if q.c().RampInterval > 0 {
+			// Start ramp from actual bounds (they may be intermediate if previous ramp isn't finished).
+			q.rampFrom, q.rampTS = q.rtp, q.clk().Now().UnixNano()
 		}
-		// Calculate actual numbers of active, sleeping and idle workers.
-		var active, sleep, idle uint
-		for i := uint32(0); i < params.WorkersMax; i++ {
+	}
+	params = q.ramp(params)
+	if params.WorkersMin == q.rtp.WorkersMin && params.WorkersMax == q.rtp.WorkersMax {
+		q.rtp = params
+		return params
+	}
+	q.rtp = params
+	if l := q.l(); q.rampTS != 0 && l != nil {
+		l.Debug("ramp", logSchedID, q.schedID, "workers_min", params.WorkersMin, "workers_max", params.WorkersMax)
+	}
+	// Gracefully stop all workers in range [workersMax...wmax].
+	// wmax is a number of maximum workers queue may have.
+	// workersMax is a maximum number of workers queue may have in current time range.
+	for i := q.wmax; i > params.WorkersMax; i-- {
+		if q.workers[i-1].getStatus() == WorkerStatusActive {
+			q.workers[i-1].signal(sigStop)
+			atomic.AddInt32(&q.workersUp, -1)
+		}
+	}
+	// Check new params.WorkersMin exceeds number of active workers.
+	if wu := uint32(q.getWorkersUp()); params.WorkersMin > wu {
+		// Start params.WorkersMin-workersUp workers to satisfy queue.
+		target := params.WorkersMin - wu
+		var c uint32
+		for i := uint32(0); i < q.wmax && c < target; i++ {
 			switch q.workers[i].getStatus() {
 			case WorkerStatusIdle:
-				idle++
+				q.workers[i].signal(sigInit)
+				q.runWorker(q.workers[i])
 			case WorkerStatusSleep:
-				sleep++
-			case WorkerStatusActive:
-				active++
+				q.workers[i].signal(sigWakeup)
+			default:
+				continue
 			}
+			c++
+			atomic.AddInt32(&q.workersUp, 1)
+		}
+	}
+	// Calculate actual numbers of active, sleeping and idle workers.
+	var active, sleep, idle uint
+	for i := uint32(0); i < params.WorkersMax; i++ {
+		switch q.workers[i].getStatus() {
+		case WorkerStatusIdle:
+			idle++
+		case WorkerStatusSleep:
+			sleep++
+		case WorkerStatusActive:
+			active++
 		}
-		// Reinitialize workers counters in metrics.
-		q.mw().WorkerSetup(active, sleep, idle)
 	}
+	// Reinitialize workers counters in metrics.
+	q.mw().WorkerSetup(active, sleep, idle)
+	return params
+}
+
+// Interpolate workers bounds if ramp is in progress.
+func (q *Queue) ramp(params realtimeParams) realtimeParams {
+	if q.rampTS == 0 {
+		return params
+	}
+	elapsed, ri := time.Duration(q.clk().Now().UnixNano()-q.rampTS), q.c().RampInterval
+	if elapsed >= ri || elapsed < 0 {
+		// Ramp is over.
+		q.rampTS = 0
+		return params
+	}
+	p := float64(elapsed) / float64(ri)
+	params.WorkersMin = lerp(q.rampFrom.WorkersMin, params.WorkersMin, p)
+	params.WorkersMax = lerp(q.rampFrom.WorkersMax, params.WorkersMax, p)
+	return params
+}
+
+// Linear interpolation between a and b.
+func lerp(a, b uint32, p float64) uint32 {
+	return uint32(math.Round(float64(a) + (float64(b)-float64(a))*p))
+}
 
-	// Calibration issues.
+// Check close and throttle conditions and update status of the queue.
+// Returns false if queue is closed and empty, i.e. balancing is senseless.
+func (q *Queue) checkStatus(rate float32, params realtimeParams) bool {
 	switch {
-	case rate == 0 && q.getStatus() == StatusClose:
+	case rate == 0 && q.getStatus() == StatusClose && !q.ovf.active():
 		// Queue is closed and empty. Force stops all active or sleeping workers.
 		for i := uint32(0); i < params.WorkersMax; i++ {
 			if ws := q.workers[i].getStatus(); ws == WorkerStatusActive || ws == WorkerStatusSleep {
 				q.workers[i].signal(sigForceStop)
 			}
 		}
-	case rate >= params.WakeupFactor:
-		// Queue fullness rate exceeds wakeupFactor. Need to start first available idle or sleeping worker.
-		if uint32(q.getWorkersUp()) == params.WorkersMax {
-			return
+		return false
+	case rate == 1:
+		// Queue is full and throttled.
+		if q.getStatus() != StatusThrottle {
+			q.setStatus(StatusThrottle)
+			q.notifyThrottle(true, false)
 		}
-		for i := params.WorkersMin; i < params.WorkersMax; i++ {
-			ws := q.workers[i].getStatus()
-			if ws == WorkerStatusActive {
-				continue
-			}
-			if ws == WorkerStatusIdle {
+	default:
+		// Restore active status after throttle.
+		if q.getStatus() == StatusThrottle && (q.breaker == nil || q.breaker.State() == BreakerStateClosed) {
+			q.setStatus(StatusActive)
+			q.notifyThrottle(false, false)
+		}
+	}
+	return true
+}
+
+// Ask balancer for target number of active workers and apply it.
+func (q *Queue) balance(rate float32, params realtimeParams) {
+	snap := q.snapshot(rate, params)
+	target := q.c().Balancer.Balance(snap)
+	if target < params.WorkersMin {
+		target = params.WorkersMin
+	}
+	if target > params.WorkersMax {
+		target = params.WorkersMax
+	}
+	if target > snap.WorkersUp && q.breaker != nil && q.breaker.State() != BreakerStateClosed {
+		// Downstream is unavailable, so new workers will not help.
+		return
+	}
+	if l := q.l(); target != snap.WorkersUp && l != nil {
+		l.Debug("balance", logRate, rate, "workers_from", snap.WorkersUp, "workers_to", target)
+	}
+	q.scale(target, params)
+}
+
+// Make snapshot of queue state for balancer.
+// Caution! Resets collected stats.
+func (q *Queue) snapshot(rate float32, params realtimeParams) BalanceSnapshot {
+	snap := BalanceSnapshot{
+		Rate:           rate,
+		WorkersUp:      uint32(q.getWorkersUp()),
+		Params:         ScheduleParams(params),
+		SleepThreshold: q.c().SleepThreshold,
+	}
+	now := q.clk().Now().UnixNano()
+	snap.Interval = time.Duration(now - atomic.SwapInt64(&q.c9nTS, now))
+
+	n, fail, ns := atomic.SwapUint64(&q.execN, 0), atomic.SwapUint64(&q.execFail, 0), atomic.SwapInt64(&q.execNs, 0)
+	enq := atomic.SwapUint64(&q.enqN, 0)
+	if n > 0 {
+		snap.Processed = n
+		snap.ExecLatency = time.Duration(ns / int64(n))
+		snap.ErrorRate = float32(fail) / float32(n)
+	}
+	if sec := snap.Interval.Seconds(); sec > 0 {
+		snap.Throughput = float64(n) / sec
+		snap.EnqueueRate = float64(enq) / sec
+	}
+	return snap
+}
+
+// Wakeup or sleep workers to reach target number of active workers.
+func (q *Queue) scale(target uint32, params realtimeParams) {
+	up := uint32(q.getWorkersUp())
+	switch {
+	case target > up:
+		// Start first available idle or sleeping workers.
+		var c uint32
+		for i := params.WorkersMin; i < params.WorkersMax && c < target-up; i++ {
+			switch q.workers[i].getStatus() {
+			case WorkerStatusIdle:
 				q.workers[i].signal(sigInit)
-				go q.workers[i].await(q)
-			} else {
+				q.runWorker(q.workers[i])
+			case WorkerStatusSleep:
 				q.workers[i].signal(sigWakeup)
+			default:
+				continue
 			}
 			atomic.AddInt32(&q.workersUp, 1)
-			// By default, only one worker starts at once. That's why need to keep heartbeat param enough small (<=1s).
-			break
-		}
-	case rate <= params.SleepFactor:
-		// Queue fullness rate fell less than sleep factor. So need to put worker(-s) to sleep.
-		if uint32(q.getWorkersUp()) == params.WorkersMin {
-			return
-		}
-		var target, c int32
-		// Workers put to sleep by chunks of workersUp / 2.
-		if target = q.getWorkersUp() / 2; target == 0 {
-			target = 1
-		}
-		// Check SleepThreshold to improve target.
-		if st := int32(q.c().SleepThreshold); st > 0 && st < target {
-			target = st
+			c++
 		}
-		for i := params.WorkersMax - 1; i >= params.WorkersMin; i-- {
+	case target < up:
+		// Put redundant active workers to sleep, starting from the last one.
+		var c uint32
+		for i := int(params.WorkersMax) - 1; i >= int(params.WorkersMin) && c < up-target; i-- {
 			if q.workers[i].getStatus() == WorkerStatusActive {
 				q.workers[i].signal(sigSleep)
+				atomic.AddInt32(&q.workersUp, -1)
 				c++
-				if uint32(atomic.AddInt32(&q.workersUp, -1)) == params.WorkersMin || c == target {
-					break
-				}
 			}
 		}
-	case rate == 1:
-		// Queue is full and throttled.
-		q.setStatus(StatusThrottle)
-	default:
-		// Restore active status after throttle.
-		if q.getStatus() == StatusThrottle {
-			q.setStatus(StatusActive)
+	}
+}
+
+// Collect execution stats for balancer.
+func (q *Queue) collectExec(spent time.Duration, err error) {
+	atomic.AddUint64(&q.execN, 1)
+	atomic.AddInt64(&q.execNs, int64(spent))
+	if err != nil {
+		atomic.AddUint64(&q.execFail, 1)
+	}
+}
+
+// Circuit breaker state change handler.
+func (q *Queue) breakerNotify(from, to BreakerState) {
+	if l := q.l(); l != nil {
+		msg, args := "breaker switch state", []any{"from", from.String(), "to", to.String()}
+		if to == BreakerStateOpen {
+			l.Warn(msg, args...)
+		} else {
+			l.Info(msg, args...)
+		}
+	}
+	q.mw().QueueBreaker(to.String())
+	switch to {
+	case BreakerStateOpen:
+		if q.casStatus(StatusActive, StatusThrottle) {
+			q.notifyThrottle(true, true)
+		}
+	case BreakerStateClosed:
+		if q.casStatus(StatusThrottle, StatusActive) {
+			q.notifyThrottle(false, true)
 		}
 	}
 }
@@ -578,7 +894,7 @@ func (q *Queue) rtParams() (params realtimeParams, schedID int) {
 	c := q.c()
 	if c.Schedule != nil {
 		var schedParams ScheduleParams
-		if schedParams, schedID = c.Schedule.Get(); schedID != -1 {
+		if schedParams, schedID = c.Schedule.GetAt(q.clk().Now()); schedID != -1 {
 			params = realtimeParams(schedParams)
 			if params.WakeupFactor == 0 {
 				params.WakeupFactor = c.WakeupFactor
@@ -597,6 +913,70 @@ func (q *Queue) rtParams() (params realtimeParams, schedID int) {
 	return
 }
 
+// Get config params overlay of schedule rule.
+func (q *Queue) schedOverlay(schedID int) *ScheduleOverlay {
+	if q.c().Schedule == nil {
+		return nil
+	}
+	return q.c().Schedule.getOverlay(schedID)
+}
+
+// Apply schedule overlay to realtime config params.
+// Nil overlay restores initial config params.
+func (q *Queue) applyOverlay(o *ScheduleOverlay) {
+	c := q.c()
+	ri, di, ld := c.RetryInterval, c.DeadlineInterval, c.LeakDirection
+	rl, rli, rlb := c.RateLimit, c.RateInterval, c.RateBurst
+	var qw map[string]uint64
+	if o != nil {
+		if o.RetryInterval > 0 {
+			ri = o.RetryInterval
+		}
+		if o.DeadlineInterval > 0 {
+			di = o.DeadlineInterval
+		}
+		if o.LeakDirection != nil {
+			ld = *o.LeakDirection
+		}
+		if o.RateLimit > 0 {
+			rl, rli, rlb = o.RateLimit, o.RateInterval, o.RateBurst
+		}
+		qw = o.QoSWeights
+		if l := q.l(); l != nil {
+			l.Info("apply schedule overlay", logSchedID, q.schedID, "retry_interval", ri, "deadline_interval", di,
+				"leak_direction", ld.String(), "rate_limit", rl, "rate_interval", rli, "qos_weights", qw)
+		}
+	}
+	atomic.StoreInt64(&q.retryInterval, int64(ri))
+	atomic.StoreInt64(&q.deadlineInterval, int64(di))
+	atomic.StoreUint32(&q.leakDirection, uint32(ld))
+	if q.tb != nil {
+		q.tb.setLimit(rl, rli, rlb)
+	}
+	if e, ok := q.engine.(*pq); ok && len(q.qosw) > 0 {
+		for i := 0; i < len(c.QoS.Queues); i++ {
+			q1 := &c.QoS.Queues[i]
+			iw, ew := q.qosw[i][0], q.qosw[i][1]
+			if w, ok := qw[q1.Name]; ok && w > 0 {
+				iw, ew = w, w
+			}
+			atomic.StoreUint64(&q1.IngressWeight, iw)
+			atomic.StoreUint64(&q1.EgressWeight, ew)
+		}
+		e.rebalancePT()
+	}
+}
+
+// Get realtime leak direction.
+func (q *Queue) getLeakDirection() LeakDirection {
+	return LeakDirection(atomic.LoadUint32(&q.leakDirection))
+}
+
+// Get realtime retry interval.
+func (q *Queue) getRetryInterval() time.Duration {
+	return time.Duration(atomic.LoadInt64(&q.retryInterval))
+}
+
 // Get number of active workers.
 func (q *Queue) getWorkersUp() int32 {
 	return atomic.LoadInt32(&q.workersUp)
@@ -612,6 +992,11 @@ func (q *Queue) getStatus() Status {
 	return Status(atomic.LoadUint32((*uint32)(&q.status)))
 }
 
+// Switch status of the queue from old to new value.
+func (q *Queue) casStatus(old, new Status) bool {
+	return atomic.CompareAndSwapUint32((*uint32)(&q.status), uint32(old), uint32(new))
+}
+
 func (q *Queue) String() string {
 	var out = struct {
 		Capacity      uint64        `json:"capacity"`
@@ -686,8 +1071,8 @@ func (q *Queue) mw() MetricsWriter {
 	return q.config.MetricsWriter
 }
 
-func (q *Queue) l() Logger {
-	return q.config.Logger
+func (q *Queue) l() StructuredLogger {
+	return q.log
 }
 
 var _ = New
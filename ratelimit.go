@@ -0,0 +1,111 @@
+package queue
+
+import (
+	"sync"
+	"time"
+)
+
+// Default rate limit interval.
+const defaultRateInterval = time.Second
+
+// Token bucket implementation.
+// Bucket refills with speed limit/interval tokens and may collect up to burst tokens.
+type tbucket struct {
+	mux sync.Mutex
+	clk Clock
+	// Tokens per nanosecond.
+	rate float64
+	// Bucket size.
+	burst float64
+	// Available tokens. May be negative due to reservations.
+	tokens float64
+	// Last refill timestamp.
+	last int64
+}
+
+// Make new token bucket. Interval and burst params are optional.
+// Zero limit means unlimited bucket.
+func newTBucket(limit uint64, interval time.Duration, burst uint64, clk Clock) *tbucket {
+	if clk == nil {
+		clk = nativeClock{}
+	}
+	b := &tbucket{
+		clk:  clk,
+		last: clk.Now().UnixNano(),
+	}
+	b.setLimit(limit, interval, burst)
+	b.tokens = b.burst
+	return b
+}
+
+// Change limit of the bucket.
+func (b *tbucket) setLimit(limit uint64, interval time.Duration, burst uint64) {
+	if interval == 0 {
+		interval = defaultRateInterval
+	}
+	if burst == 0 {
+		burst = 1
+	}
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.refill()
+	b.rate, b.burst = float64(limit)/float64(interval), float64(burst)
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
+
+// Reserve one token and return how long need to wait before use it.
+func (b *tbucket) reserve() time.Duration {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	if b.rate == 0 {
+		// Bucket is unlimited.
+		return 0
+	}
+	b.refill()
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate)
+}
+
+// Try to take one token without waiting.
+func (b *tbucket) take() bool {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	if b.rate == 0 {
+		return true
+	}
+	b.refill()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Return unused token back to the bucket.
+func (b *tbucket) refund() {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	if b.rate == 0 {
+		return
+	}
+	if b.tokens++; b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
+
+// Refill the bucket considering time passed since last refill.
+// Caution! Must be called under mutex.
+func (b *tbucket) refill() {
+	now := b.clk.Now().UnixNano()
+	if delta := now - b.last; delta > 0 && b.rate > 0 {
+		if b.tokens += float64(delta) * b.rate; b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+	}
+	b.last = now
+}
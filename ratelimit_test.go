@@ -0,0 +1,102 @@
+package queue
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/koykov/queue/qos"
+)
+
+func TestRateLimit(t *testing.T) {
+	t.Run("reserve", func(t *testing.T) {
+		clk := newTestClock(time.Now())
+		tb := newTBucket(10, time.Second, 2, clk)
+		// Burst allows two items at once.
+		for i := 0; i < 2; i++ {
+			if d := tb.reserve(); d != 0 {
+				t.Fatalf("delay mismatch: need 0, got %s", d)
+			}
+		}
+		if d := tb.reserve(); d != time.Millisecond*100 {
+			t.Errorf("delay mismatch: need 100ms, got %s", d)
+		}
+		if d := tb.reserve(); d != time.Millisecond*200 {
+			t.Errorf("delay mismatch: need 200ms, got %s", d)
+		}
+		clk.Add(time.Millisecond * 200)
+		if d := tb.reserve(); d != time.Millisecond*100 {
+			t.Errorf("delay mismatch: need 100ms, got %s", d)
+		}
+	})
+	t.Run("take", func(t *testing.T) {
+		clk := newTestClock(time.Now())
+		tb := newTBucket(1, time.Second, 0, clk)
+		if !tb.take() {
+			t.Fatal("take failed")
+		}
+		if tb.take() {
+			t.Fatal("take exceeds limit")
+		}
+		clk.Add(time.Second)
+		if !tb.take() {
+			t.Fatal("bucket doesn't refill")
+		}
+		tb.refund()
+		if !tb.take() {
+			t.Fatal("refund failed")
+		}
+	})
+	t.Run("idle", func(t *testing.T) {
+		w := &countWorker{}
+		q, err := New(&Config{
+			Capacity:  10,
+			Workers:   4,
+			Worker:    w,
+			RateLimit: 10,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Idle workers must not hold tokens reserved before items come.
+		time.Sleep(time.Millisecond * 350)
+		for i := 0; i < 4; i++ {
+			_ = q.Enqueue(i)
+		}
+		time.Sleep(time.Millisecond * 40)
+		if n := atomic.LoadUint32(&w.n); n != 1 {
+			t.Errorf("burst mismatch: need 1, got %d", n)
+		}
+		_ = q.ForceClose()
+	})
+	t.Run("subq", func(t *testing.T) {
+		clk := newTestClock(time.Now())
+		conf := Config{
+			QoS: qos.New(qos.RR, qos.DummyPriorityEvaluator{}).
+				AddQueue(qos.Queue{Name: "limited", Capacity: 10, Weight: 100, RateLimit: 1}).
+				AddQueue(qos.Queue{Name: "free", Capacity: 10, Weight: 100}),
+			Clock:         clk,
+			MetricsWriter: DummyMetrics{},
+		}
+		_ = conf.QoS.Validate()
+		e := pq{conf: &conf, tb: []*tbucket{newTBucket(1, 0, 0, clk), nil},
+			subq: []chan item{make(chan item, 10), make(chan item, 10)}}
+		_ = e.egress.init(&conf.QoS.Egress)
+		for i := 0; i < 3; i++ {
+			e.subq[0] <- item{}
+			e.subq[1] <- item{}
+		}
+		var n0, n1 int
+		for i := 0; i < 3; i++ {
+			if e.shift(0) {
+				n0++
+			}
+			if e.shift(1) {
+				n1++
+			}
+		}
+		if n0 != 1 || n1 != 3 {
+			t.Errorf("shift mismatch: need 1/3, got %d/%d", n0, n1)
+		}
+	})
+}
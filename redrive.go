@@ -0,0 +1,159 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RedriveConfig describes params of Redriver.
+type RedriveConfig struct {
+	// Source of items (usually DLQ).
+	// Mandatory param.
+	Source Dequeuer
+	// Target to forward items (usually the main queue).
+	// Mandatory param.
+	Target Enqueuer
+	// Skip catches items that don't match Reasons filter (and all items in dry run mode).
+	// If this param omit skipped items will drop, so better to use it with destructive sources (like Queue).
+	// Setting the source itself is possible together with Limit param.
+	Skip Enqueuer
+
+	// Reasons filter. Only dead letters with given reasons will forward to target.
+	// Raw (non-enveloped) items match only empty filter.
+	// If this param omit all items will forward.
+	Reasons []DLQReason
+	// Limit of items to read from source.
+	// If this param omit redriver will read source until it's empty.
+	Limit uint64
+	// DryRun only counts matching items, nothing forwards to target.
+	DryRun bool
+
+	// RateLimit limits the number of items forwarding to target per RateInterval.
+	// If this param omit items will forward as fast as possible.
+	RateLimit uint64
+	// RateInterval is a period of RateLimit.
+	// If this param omit defaultRateInterval (1 second) will use instead.
+	RateInterval time.Duration
+	// RateBurst indicates how many items may forward at once above the RateLimit.
+	// If this param omit 1 will use instead.
+	RateBurst uint64
+
+	// Clock represents clock keeper.
+	// If this param omit nativeClock will use instead (see clock.go).
+	Clock Clock
+}
+
+// RedriveStats contains counters of redrive run.
+type RedriveStats struct {
+	// Items read from source.
+	Read uint64
+	// Items matched by filter.
+	Matched uint64
+	// Items forwarded to target.
+	Moved uint64
+	// Items sent to skip (or dropped).
+	Skipped uint64
+}
+
+// Redriver moves items from DLQ (or any other Dequeuer) back to the queue, eg: after outage is over.
+//
+// Dead letters (see DeadLetter) unwrap before forwarding, so target receives original payloads.
+type Redriver struct {
+	conf RedriveConfig
+	tb   *tbucket
+}
+
+// NewRedriver makes new redriver instance.
+func NewRedriver(config RedriveConfig) (*Redriver, error) {
+	if config.Source == nil {
+		return nil, ErrNoSource
+	}
+	if config.Target == nil && !config.DryRun {
+		return nil, ErrNoTarget
+	}
+	if config.Clock == nil {
+		config.Clock = nativeClock{}
+	}
+	r := &Redriver{conf: config}
+	if config.RateLimit > 0 {
+		r.tb = newTBucket(config.RateLimit, config.RateInterval, config.RateBurst, config.Clock)
+	}
+	return r, nil
+}
+
+// Run reads source until it's empty (or Limit reached) and forwards matching items to target.
+//
+// Target error stops redrive, failed item sends to skip. Context cancellation stops redrive as well.
+func (r *Redriver) Run(ctx context.Context) (stats RedriveStats, err error) {
+	c := &r.conf
+	for c.Limit == 0 || stats.Read < c.Limit {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		x, ok := c.Source.Dequeue()
+		if !ok {
+			return
+		}
+		stats.Read++
+		match := r.match(x)
+		if match {
+			stats.Matched++
+		}
+		if !match || c.DryRun {
+			r.skip(x, &stats)
+			continue
+		}
+
+		if r.tb != nil {
+			if delay := r.tb.reserve(); delay > 0 {
+				t := time.NewTimer(delay)
+				select {
+				case <-t.C:
+				case <-ctx.Done():
+					t.Stop()
+					r.skip(x, &stats)
+					err = ctx.Err()
+					return
+				}
+			}
+		}
+
+		payload := x
+		if dl, ok := x.(*DeadLetter); ok {
+			payload = dl.Payload
+		}
+		if err = c.Target.Enqueue(payload); err != nil {
+			r.skip(x, &stats)
+			err = fmt.Errorf("redrive: %w", err)
+			return
+		}
+		stats.Moved++
+	}
+	return
+}
+
+// Check if item matches reasons filter.
+func (r *Redriver) match(x any) bool {
+	if len(r.conf.Reasons) == 0 {
+		return true
+	}
+	dl, ok := x.(*DeadLetter)
+	if !ok {
+		return false
+	}
+	for _, reason := range r.conf.Reasons {
+		if dl.Reason == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// Send item to skip enqueuer.
+func (r *Redriver) skip(x any, stats *RedriveStats) {
+	stats.Skipped++
+	if r.conf.Skip != nil {
+		_ = r.conf.Skip.Enqueue(x)
+	}
+}
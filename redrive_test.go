@@ -0,0 +1,96 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type sliceQueue struct {
+	items []any
+}
+
+func (q *sliceQueue) Enqueue(x any) error {
+	q.items = append(q.items, x)
+	return nil
+}
+
+func (q *sliceQueue) Dequeue() (any, bool) {
+	if len(q.items) == 0 {
+		return nil, false
+	}
+	x := q.items[0]
+	q.items = q.items[1:]
+	return x, true
+}
+
+func TestRedriver(t *testing.T) {
+	letters := func() *sliceQueue {
+		return &sliceQueue{items: []any{
+			&DeadLetter{Payload: 1, Reason: DLQReasonFail},
+			&DeadLetter{Payload: 2, Reason: DLQReasonLeak},
+			&DeadLetter{Payload: 3, Reason: DLQReasonFail},
+			"raw",
+		}}
+	}
+	t.Run("filter", func(t *testing.T) {
+		dst, skip := &sliceQueue{}, &sliceQueue{}
+		r, err := NewRedriver(RedriveConfig{Source: letters(), Target: dst, Skip: skip, Reasons: []DLQReason{DLQReasonFail}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		stats, err := r.Run(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats != (RedriveStats{Read: 4, Matched: 2, Moved: 2, Skipped: 2}) {
+			t.Errorf("stats mismatch: %+v", stats)
+		}
+		if len(dst.items) != 2 || dst.items[0] != 1 || dst.items[1] != 3 || len(skip.items) != 2 {
+			t.Errorf("items mismatch: %v / %v", dst.items, skip.items)
+		}
+	})
+	t.Run("dry run", func(t *testing.T) {
+		src := letters()
+		r, _ := NewRedriver(RedriveConfig{Source: src, Skip: src, Limit: 4, DryRun: true})
+		stats, _ := r.Run(context.Background())
+		if stats.Matched != 4 || stats.Moved != 0 || len(src.items) != 4 {
+			t.Errorf("dry run mismatch: %+v", stats)
+		}
+	})
+	t.Run("queue", func(t *testing.T) {
+		w := &blockWorker{in: make(chan struct{}), out: make(chan struct{})}
+		src, err := New(&Config{Capacity: 10, Workers: 1, Worker: w})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = src.Enqueue("busy")
+		<-w.in
+		for i := 0; i < 5; i++ {
+			_ = src.Enqueue(i)
+		}
+		dst := &sliceQueue{}
+		r, _ := NewRedriver(RedriveConfig{Source: src, Target: dst, Limit: 3})
+		stats, _ := r.Run(context.Background())
+		if stats.Moved != 3 || src.Size() != 2 || dst.items[0] != 0 {
+			t.Errorf("queue redrive mismatch: %+v, %v", stats, dst.items)
+		}
+		close(w.out)
+		go func() {
+			for range w.in {
+			}
+		}()
+		_ = src.Close()
+	})
+	t.Run("error", func(t *testing.T) {
+		if _, err := NewRedriver(RedriveConfig{Target: &sliceQueue{}}); !errors.Is(err, ErrNoSource) {
+			t.Errorf("error mismatch: %v", err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		r, _ := NewRedriver(RedriveConfig{Source: letters(), Target: &sliceQueue{}})
+		if _, err := r.Run(ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("error mismatch: %v", err)
+		}
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -19,21 +20,83 @@ const (
 
 // Schedule describes time ranges with specific queue params.
 type Schedule struct {
+	mux sync.Mutex
 	buf []schedRule
 	srt bool
+	// Lookup index of the actual day.
+	idx *schedIndex
+	// Location to evaluate time ranges. Process local zone uses by default.
+	loc *time.Location
+	// Clock keeper. Queue overwrites it with its own Config.Clock.
+	clk Clock
 }
 
 type schedRule struct {
 	// Left and right daily timestamps.
+	// Right timestamp less than left means that range wraps past midnight.
 	lt, rt uint32
+	// Weekdays bitmask (see time.Weekday). Zero value means every day.
+	days uint8
+	// First and last days (days since Unix epoch) of dates range. Uses only if dates flag is set.
+	dl, dr int32
+	dates  bool
 	// Params to use between lt and rt.
 	params ScheduleParams
+	// Optional config params overlay.
+	overlay *ScheduleOverlay
+}
+
+// Rule precedence: dates rules overlap weekdays rules, weekdays rules overlap daily rules.
+func (r *schedRule) rank() int {
+	switch {
+	case r.dates:
+		return 2
+	case r.days != 0:
+		return 1
+	}
+	return 0
+}
+
+// Check if rule applies to the given day (days since Unix epoch).
+func (r *schedRule) applies(day int32) bool {
+	if r.dates && (day < r.dl || day > r.dr) {
+		return false
+	}
+	if r.days != 0 && r.days&(1<<uint(dayWeekday(day))) == 0 {
+		return false
+	}
+	return true
+}
+
+// Check if rule wraps past midnight.
+func (r *schedRule) wraps() bool {
+	return r.rt < r.lt
 }
 
 // ScheduleParams describes queue params for specific time range.
 type ScheduleParams realtimeParams
 
+// ScheduleOverlay describes optional config params that overwrite corresponding config's values for specific time
+// range. Zero values mean no overwriting.
+type ScheduleOverlay struct {
+	// RetryInterval overwrites Config.RetryInterval.
+	RetryInterval time.Duration
+	// DeadlineInterval overwrites Config.DeadlineInterval.
+	DeadlineInterval time.Duration
+	// RateLimit, RateInterval and RateBurst overwrite corresponding Config's rate limit params.
+	RateLimit    uint64
+	RateInterval time.Duration
+	RateBurst    uint64
+	// LeakDirection overwrites Config.LeakDirection. Nil value means no overwriting.
+	LeakDirection *LeakDirection
+	// QoSWeights overwrites weights (both ingress and egress) of QoS sub-queues by sub-queue name.
+	QoSWeights map[string]uint64
+}
+
 var (
+	weekdays = [7]string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+	reDate  = regexp.MustCompile(`^(\d{4})-(\d{2})-(\d{2})$`)
 	reHMSMs = regexp.MustCompile(`(\d{2}):(\d{2}):(\d{2})\.(\d{3})`)
 	reHMS   = regexp.MustCompile(`(\d{2}):(\d{2}):(\d{2})`)
 	reHM    = regexp.MustCompile(`(\d{2}):(\d{2})`)
@@ -46,14 +109,67 @@ func NewSchedule() *Schedule {
 	return s
 }
 
+// SetLocation sets location to evaluate time ranges in.
+// All time ranges means wall clock time of the location, so DST transitions consider automatically: eg, range
+// `02:00-03:00` will skip the day when clocks turn forward and will hit twice the day when clocks turn back.
+// If this param omit process local zone (time.Local) will use instead.
+func (s *Schedule) SetLocation(loc *time.Location) *Schedule {
+	s.loc = loc
+	return s
+}
+
+// SetClock sets clock keeper to get current time.
+// Queue overwrites it with its own Config.Clock. If this param omit nativeClock will use instead.
+func (s *Schedule) SetClock(clock Clock) *Schedule {
+	s.clk = clock
+	return s
+}
+
 // AddRange registers specific params for given time range.
-// Raw specifies time range in format `<left time>-<right time>`. Time point may be in three formats:
+// Raw specifies time range in format `[<dates>] [<weekdays>] <left time>-<right time>`. Time point may be in three
+// formats:
 // * HH:MM
 // * HH:MM:SS
 // * HH:MM:SS.MSC (msc is a millisecond 0-999).
+// Optional weekdays limit the rule to given days of week. Weekdays specifies as comma separated list of days or days
+// ranges, eg: `mon-fri`, `sat,sun` or `mon,wed-fri`.
+// Optional dates limit the rule to given calendar dates. Dates specifies as single date `YYYY-MM-DD` or dates range
+// `YYYY-MM-DD..YYYY-MM-DD` (both including).
+// Examples:
+// * `08:00-12:00` - every day from 8:00 AM till 12:00 AM
+// * `sat,sun 00:00-*` - the whole weekend
+// * `2026-11-27 00:00-*` - Black Friday
+// * `2026-12-01..2026-12-31 mon-fri 09:00-18:00` - working hours of December
+// * `fri 22:00-06:00` - night from friday to saturday
+// Range with right time less than left wraps past midnight and belongs to the day it starts, eg: weekdays and dates
+// limits of range `22:00-06:00` check considering 22:00 moment.
+// Ranges with the same precedence cannot overlap, ErrSchedOverlap will return on conflict.
+// On overlap dates rules take precedence over weekdays rules and weekdays rules take precedence over daily rules.
 // All time ranges outside registered will use default params specified in config (WorkersMin, WorkersMax, WakeupFactor
 // and SleepFactor).
-func (s *Schedule) AddRange(raw string, params ScheduleParams) (err error) {
+func (s *Schedule) AddRange(raw string, params ScheduleParams) error {
+	return s.add(raw, params, nil)
+}
+
+// AddRangeOverlay registers specific params for given time range together with overlay of config params.
+// See AddRange for raw format details.
+func (s *Schedule) AddRangeOverlay(raw string, params ScheduleParams, overlay ScheduleOverlay) error {
+	if len(overlay.QoSWeights) > 0 {
+		// Protect weights from changing after registration.
+		w := make(map[string]uint64, len(overlay.QoSWeights))
+		for k, v := range overlay.QoSWeights {
+			w[k] = v
+		}
+		overlay.QoSWeights = w
+	}
+	if overlay.LeakDirection != nil {
+		ld := *overlay.LeakDirection
+		overlay.LeakDirection = &ld
+	}
+	return s.add(raw, params, &overlay)
+}
+
+func (s *Schedule) add(raw string, params ScheduleParams, overlay *ScheduleOverlay) (err error) {
 	if params.WorkersMax == 0 {
 		return ErrSchedZeroMax
 	}
@@ -61,6 +177,32 @@ func (s *Schedule) AddRange(raw string, params ScheduleParams) (err error) {
 		return ErrSchedMinGtMax
 	}
 
+	var rule schedRule
+	// Parse optional dates and weekdays.
+	tokens := strings.Fields(raw)
+	if len(tokens) == 0 {
+		return ErrSchedBadRange
+	}
+	for _, tkn := range tokens[:len(tokens)-1] {
+		if len(tkn) > 0 && tkn[0] >= '0' && tkn[0] <= '9' {
+			if rule.dates {
+				return ErrSchedBadDate
+			}
+			if rule.dl, rule.dr, err = s.parseDates(tkn); err != nil {
+				return
+			}
+			rule.dates = true
+		} else {
+			if rule.days != 0 {
+				return ErrSchedBadDays
+			}
+			if rule.days, err = s.parseDays(tkn); err != nil {
+				return
+			}
+		}
+	}
+	raw = tokens[len(tokens)-1]
+
 	var pos int
 	if pos = strings.Index(raw, "-"); pos == -1 {
 		return ErrSchedBadRange
@@ -70,49 +212,157 @@ func (s *Schedule) AddRange(raw string, params ScheduleParams) (err error) {
 		return ErrSchedBadRange
 	}
 
-	var (
-		lt, rt uint32
-	)
-	if lt, err = s.parse(l, 0); err != nil {
+	if rule.lt, err = s.parse(l, 0); err != nil {
 		return err
 	}
-	if rt, err = s.parse(r, 1); err != nil {
+	if rule.rt, err = s.parse(r, 1); err != nil {
 		return err
 	}
-	if rt < lt {
+	if rule.rt == rule.lt {
 		return ErrSchedBadRange
 	}
-	s.srt = false
-	s.buf = append(s.buf, schedRule{
-		lt: lt, rt: rt,
-		params: params,
-	})
+	rule.params, rule.overlay = params, overlay
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	for i := 0; i < len(s.buf); i++ {
+		if s.buf[i].overlaps(&rule) {
+			return ErrSchedOverlap
+		}
+	}
+	s.srt, s.idx = false, nil
+	s.buf = append(s.buf, rule)
 	return nil
 }
 
 // Get returns queue params if current time hits to the one of registered ranges.
 // Param schedID indicates which time range hits and contains -1 on miss.
 func (s *Schedule) Get() (params ScheduleParams, schedID int) {
+	var clk Clock = nativeClock{}
+	if s.clk != nil {
+		clk = s.clk
+	}
+	return s.GetAt(clk.Now())
+}
+
+// GetAt returns queue params if given time hits to the one of registered ranges.
+// Param schedID indicates which time range hits and contains -1 on miss.
+func (s *Schedule) GetAt(now time.Time) (params ScheduleParams, schedID int) {
 	l := len(s.buf)
 	if l == 0 {
 		return
 	}
-	schedID = -1
-	s.sort()
-	now := time.Now()
+	now = now.In(s.location())
 	h, m, sc, ms := now.Hour(), now.Minute(), now.Second(), now.Nanosecond()/1e6
 	t := uint32(h*msHour + m*msMin + sc*msSec + ms)
-	_ = s.buf[l-1]
-	for i := 0; i < l; i++ {
-		r := s.buf[i]
-		if r.lt <= t && r.rt > t {
-			params, schedID = r.params, i
-			return
+	day := civilDay(now)
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.sort()
+	if s.idx == nil || s.idx.day != day {
+		// Day changed, so rebuild index.
+		s.idx = s.buildIndex(day)
+	}
+	if schedID = s.idx.get(t); schedID == -1 {
+		return
+	}
+	params = s.buf[schedID].params
+	return
+}
+
+// Get overlay of the rule by schedID.
+func (s *Schedule) getOverlay(schedID int) *ScheduleOverlay {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if schedID < 0 || schedID >= len(s.buf) {
+		return nil
+	}
+	return s.buf[schedID].overlay
+}
+
+// Check if any rule overwrites rate limit.
+func (s *Schedule) hasRateOverlay() bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	for i := 0; i < len(s.buf); i++ {
+		if o := s.buf[i].overlay; o != nil && o.RateLimit > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse weekdays list.
+func (s *Schedule) parseDays(raw string) (days uint8, err error) {
+	idx := func(raw string) (int, bool) {
+		raw = strings.ToLower(raw)
+		for i := 0; i < len(weekdays); i++ {
+			if weekdays[i] == raw {
+				return i, true
+			}
+		}
+		return 0, false
+	}
+	for _, part := range strings.Split(raw, ",") {
+		l, r := part, part
+		if pos := strings.Index(part, "-"); pos != -1 {
+			l, r = part[:pos], part[pos+1:]
+		}
+		li, ok := idx(l)
+		if !ok {
+			return 0, ErrSchedBadDays
+		}
+		ri, ok := idx(r)
+		if !ok {
+			return 0, ErrSchedBadDays
+		}
+		// Weekdays range may wrap through the week end, eg: fri-mon.
+		for i := li; ; i = (i + 1) % 7 {
+			days |= 1 << uint(i)
+			if i == ri {
+				break
+			}
 		}
 	}
 	return
 }
 
+// Parse single date or dates range and convert them to days since Unix epoch.
+func (s *Schedule) parseDates(raw string) (dl, dr int32, err error) {
+	l, r := raw, raw
+	if pos := strings.Index(raw, ".."); pos != -1 {
+		l, r = raw[:pos], raw[pos+2:]
+	}
+	if dl, err = s.parseDate(l); err != nil {
+		return
+	}
+	if dr, err = s.parseDate(r); err != nil {
+		return
+	}
+	if dr < dl {
+		err = ErrSchedBadDate
+	}
+	return
+}
+
+// Parse date in format YYYY-MM-DD.
+func (s *Schedule) parseDate(raw string) (int32, error) {
+	x := reDate.FindStringSubmatch(raw)
+	if len(x) == 0 {
+		return 0, ErrSchedBadDate
+	}
+	y, _ := strconv.Atoi(x[1])
+	m, _ := strconv.Atoi(x[2])
+	d, _ := strconv.Atoi(x[3])
+	t := time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.UTC)
+	if t.Year() != y || int(t.Month()) != m || t.Day() != d {
+		// Date normalized, so it doesn't exist (eg: 2026-02-30).
+		return 0, ErrSchedBadDate
+	}
+	return civilDay(t), nil
+}
+
 // Parse and convert time point to daily timestamp.
 func (s *Schedule) parse(raw string, target int) (t uint32, err error) {
 	var h, m, sc, ms int
@@ -122,6 +372,7 @@ func (s *Schedule) parse(raw string, target int) (t uint32, err error) {
 			return
 		default:
 			t = uint32(23*msHour + 59*msMin + 59*msSec + 999*msItself)
+			return
 		}
 	}
 	if x := reHMSMs.FindStringSubmatch(raw); len(x) > 0 {
@@ -177,12 +428,38 @@ func (s *Schedule) WorkersMaxDaily() (max uint32) {
 }
 
 func (s *Schedule) String() string {
+	s.mux.Lock()
+	defer s.mux.Unlock()
 	s.sort()
 	var buf bytes.Buffer
 	_, _ = buf.WriteString("[\n")
 	for i := 0; i < len(s.buf); i++ {
 		r := s.buf[i]
 		_ = buf.WriteByte('\t')
+		if r.dates {
+			_, _ = buf.WriteString(s.fmtDate(r.dl))
+			if r.dr != r.dl {
+				_, _ = buf.WriteString("..")
+				_, _ = buf.WriteString(s.fmtDate(r.dr))
+			}
+			_ = buf.WriteByte(' ')
+		}
+		if r.days != 0 {
+			var c int
+			for j := 0; j < 7; j++ {
+				// Start the week from monday.
+				wd := (j + 1) % 7
+				if r.days&(1<<uint(wd)) == 0 {
+					continue
+				}
+				if c > 0 {
+					_ = buf.WriteByte(',')
+				}
+				_, _ = buf.WriteString(weekdays[wd])
+				c++
+			}
+			_ = buf.WriteByte(' ')
+		}
 		_, _ = buf.WriteString(s.fmtTime(r.lt))
 		_ = buf.WriteByte('-')
 		_, _ = buf.WriteString(s.fmtTime(r.rt))
@@ -194,6 +471,9 @@ func (s *Schedule) String() string {
 		_, _ = buf.WriteString(strconv.FormatFloat(float64(r.params.WakeupFactor), 'f', -1, 32))
 		_, _ = buf.WriteString(" sleep: ")
 		_, _ = buf.WriteString(strconv.FormatFloat(float64(r.params.SleepFactor), 'f', -1, 32))
+		if o := r.overlay; o != nil {
+			s.fmtOverlay(&buf, o)
+		}
 		if i > 0 {
 			_ = buf.WriteByte(',')
 		}
@@ -215,21 +495,79 @@ func (s *Schedule) fmtTime(t uint32) string {
 	return fmt.Sprintf("%02d:%02d:%02d.%03d", h, m, sc, ms)
 }
 
+// Format non-empty overlay params.
+func (s *Schedule) fmtOverlay(buf *bytes.Buffer, o *ScheduleOverlay) {
+	if o.RetryInterval > 0 {
+		_, _ = buf.WriteString(" retry: ")
+		_, _ = buf.WriteString(o.RetryInterval.String())
+	}
+	if o.DeadlineInterval > 0 {
+		_, _ = buf.WriteString(" deadline: ")
+		_, _ = buf.WriteString(o.DeadlineInterval.String())
+	}
+	if o.RateLimit > 0 {
+		_, _ = buf.WriteString(" rate: ")
+		_, _ = buf.WriteString(strconv.FormatUint(o.RateLimit, 10))
+		if o.RateInterval > 0 {
+			_ = buf.WriteByte('/')
+			_, _ = buf.WriteString(o.RateInterval.String())
+		}
+		if o.RateBurst > 0 {
+			_, _ = buf.WriteString(" burst: ")
+			_, _ = buf.WriteString(strconv.FormatUint(o.RateBurst, 10))
+		}
+	}
+	if o.LeakDirection != nil {
+		_, _ = buf.WriteString(" leak: ")
+		_, _ = buf.WriteString(o.LeakDirection.String())
+	}
+	if len(o.QoSWeights) > 0 {
+		names := make([]string, 0, len(o.QoSWeights))
+		for name := range o.QoSWeights {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		_, _ = buf.WriteString(" weights: ")
+		for i, name := range names {
+			if i > 0 {
+				_ = buf.WriteByte(',')
+			}
+			_, _ = buf.WriteString(name)
+			_ = buf.WriteByte('=')
+			_, _ = buf.WriteString(strconv.FormatUint(o.QoSWeights[name], 10))
+		}
+	}
+}
+
+// Format days since Unix epoch to date.
+func (s *Schedule) fmtDate(day int32) string {
+	return time.Unix(int64(day)*86400, 0).UTC().Format("2006-01-02")
+}
+
 // Copy copies schedule instance to protect queue from changing params after start.
 // It means that after starting queue all schedule modifications will have no effect.
 func (s *Schedule) Copy() *Schedule {
+	s.mux.Lock()
+	defer s.mux.Unlock()
 	s.sort()
-	cpy := &Schedule{}
+	cpy := &Schedule{srt: s.srt, loc: s.loc, clk: s.clk}
 	cpy.buf = append(cpy.buf, s.buf...)
 	return cpy
 }
 
+func (s *Schedule) location() *time.Location {
+	if s.loc == nil {
+		return time.Local
+	}
+	return s.loc
+}
+
 func (s *Schedule) sort() {
 	if s.srt == true {
 		return
 	}
-	s.srt = true
-	sort.Sort(s)
+	s.srt, s.idx = true, nil
+	sort.Stable(s)
 }
 
 func (s *Schedule) Len() int {
@@ -237,9 +575,24 @@ func (s *Schedule) Len() int {
 }
 
 func (s *Schedule) Less(i, j int) bool {
+	ri, rj := s.buf[i].rank(), s.buf[j].rank()
+	if ri != rj {
+		return ri > rj
+	}
 	return s.buf[i].lt < s.buf[j].lt
 }
 
+// Get number of days since Unix epoch considering location of t.
+func civilDay(t time.Time) int32 {
+	y, m, d := t.Date()
+	return int32(time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix() / 86400)
+}
+
+// Get weekday of the day since Unix epoch (1970-01-01 is thursday).
+func dayWeekday(day int32) time.Weekday {
+	return time.Weekday((day%7 + 11) % 7)
+}
+
 func (s *Schedule) Swap(i, j int) {
 	s.buf[i], s.buf[j] = s.buf[j], s.buf[i]
 }
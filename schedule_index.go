@@ -0,0 +1,123 @@
+package queue
+
+import "sort"
+
+// Length of the day in milliseconds.
+const msDay = 24 * msHour
+
+// Schedule lookup index of the certain day.
+//
+// Index contains non-overlapping sorted segments of the day considering rules precedence and ranges wrapped from the
+// previous day. Index builds once per day, so lookup costs O(log n).
+type schedIndex struct {
+	day  int32
+	segs []schedSeg
+}
+
+// Segment of the day [lt...rt) belongs to rule with index id.
+type schedSeg struct {
+	lt, rt uint32
+	id     int
+}
+
+// Build index of the given day.
+// Caution! Rules must be sorted by precedence.
+func (s *Schedule) buildIndex(day int32) *schedIndex {
+	idx := &schedIndex{day: day}
+	for i := 0; i < len(s.buf); i++ {
+		var buf [2]schedSeg
+		for _, seg := range s.buf[i].segments(day, buf[:0]) {
+			seg.id = i
+			idx.paint(seg)
+		}
+	}
+	return idx
+}
+
+// Get rule index by daily timestamp.
+func (idx *schedIndex) get(t uint32) int {
+	// Find first segment ends after t.
+	i := sort.Search(len(idx.segs), func(i int) bool { return idx.segs[i].rt > t })
+	if i < len(idx.segs) && idx.segs[i].lt <= t {
+		return idx.segs[i].id
+	}
+	return -1
+}
+
+// Add segment to the index excluding parts already covered by other segments (with higher precedence).
+func (idx *schedIndex) paint(seg schedSeg) {
+	for i := 0; i < len(idx.segs) && seg.lt < seg.rt; i++ {
+		cur := idx.segs[i]
+		if cur.rt <= seg.lt {
+			continue
+		}
+		if cur.lt >= seg.rt {
+			break
+		}
+		if seg.lt < cur.lt {
+			// Insert the left free part.
+			idx.insert(i, schedSeg{lt: seg.lt, rt: cur.lt, id: seg.id})
+			i++
+		}
+		seg.lt = cur.rt
+	}
+	if seg.lt < seg.rt {
+		i := sort.Search(len(idx.segs), func(i int) bool { return idx.segs[i].lt >= seg.rt })
+		idx.insert(i, seg)
+	}
+}
+
+func (idx *schedIndex) insert(i int, seg schedSeg) {
+	idx.segs = append(idx.segs, schedSeg{})
+	copy(idx.segs[i+1:], idx.segs[i:])
+	idx.segs[i] = seg
+}
+
+// Get segments of the rule at the given day.
+// Wrapped rule gives two segments: the tail of previous day range and the head of actual day range.
+func (r *schedRule) segments(day int32, buf []schedSeg) []schedSeg {
+	if r.wraps() {
+		if r.applies(day - 1) {
+			buf = append(buf, schedSeg{lt: 0, rt: r.rt})
+		}
+		if r.applies(day) {
+			buf = append(buf, schedSeg{lt: r.lt, rt: msDay})
+		}
+		return buf
+	}
+	if r.applies(day) {
+		buf = append(buf, schedSeg{lt: r.lt, rt: r.rt})
+	}
+	return buf
+}
+
+// Check if rules with the same precedence overlap.
+func (r *schedRule) overlaps(r1 *schedRule) bool {
+	if r.rank() != r1.rank() {
+		// Rules with different precedence may overlap.
+		return false
+	}
+	// Daily and weekdays rules repeats every week, so check any week. 1970-01-05 (day 4) was monday.
+	lo, hi := int32(4), int32(11)
+	if r.dates {
+		// Check dates intersection including the day after due to wrapped ranges.
+		if lo = r.dl; r1.dl > lo {
+			lo = r1.dl
+		}
+		if hi = r.dr; r1.dr < hi {
+			hi = r1.dr
+		}
+		hi += 2
+	}
+	var buf, buf1 [2]schedSeg
+	for day := lo; day < hi; day++ {
+		for _, a := range r.segments(day, buf[:0]) {
+			for _, b := range r1.segments(day, buf1[:0]) {
+				if a.lt < b.rt && b.lt < a.rt {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
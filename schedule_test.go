@@ -1,6 +1,14 @@
 package queue
 
-import "testing"
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/koykov/queue/qos"
+)
 
 func TestSchedule(t *testing.T) {
 	t.Run("min > max", func(t *testing.T) {
@@ -61,3 +69,376 @@ func TestSchedule(t *testing.T) {
 		}
 	})
 }
+
+func TestScheduleCalendar(t *testing.T) {
+	t.Run("bad days", func(t *testing.T) {
+		s := NewSchedule()
+		for _, r := range []string{"foo 08:00-12:00", "mon-foo 08:00-12:00", "mon sun 08:00-12:00"} {
+			if err := s.AddRange(r, ScheduleParams{0, 1, 0, 0}); err != ErrSchedBadDays {
+				t.Errorf("bad error: need %s, got %s", ErrSchedBadDays, err)
+			}
+		}
+	})
+	t.Run("bad date", func(t *testing.T) {
+		s := NewSchedule()
+		for _, r := range []string{"2026-02-30 08:00-12:00", "2026-12-01..2026-11-01 08:00-12:00", "2026-1-1 08:00-12:00"} {
+			if err := s.AddRange(r, ScheduleParams{0, 1, 0, 0}); err != ErrSchedBadDate {
+				t.Errorf("bad error: need %s, got %s", ErrSchedBadDate, err)
+			}
+		}
+	})
+	t.Run("string", func(t *testing.T) {
+		exp := `[
+	2026-11-27 00:00:00.000-23:59:59.999 min: 32 max: 64 wakeup: 0 sleep: 0
+	2026-12-01..2026-12-31 mon,tue,wed,thu,fri 09:00:00.000-18:00:00.000 min: 4 max: 8 wakeup: 0 sleep: 0,
+	sat,sun 00:00:00.000-23:59:59.999 min: 1 max: 2 wakeup: 0 sleep: 0,
+	08:00:00.000-12:00:00.000 min: 4 max: 16 wakeup: 0 sleep: 0,
+]`
+		s := NewSchedule()
+		_ = s.AddRange("08:00-12:00", ScheduleParams{4, 16, 0, 0})
+		_ = s.AddRange("Sat,sun 00:00-*", ScheduleParams{1, 2, 0, 0})
+		_ = s.AddRange("2026-12-01..2026-12-31 mon-fri 09:00-18:00", ScheduleParams{4, 8, 0, 0})
+		_ = s.AddRange("2026-11-27 00:00-*", ScheduleParams{32, 64, 0, 0})
+		if s.String() != exp {
+			t.Errorf("string mismatch: got %s", s.String())
+		}
+		if cpy := s.Copy(); cpy.String() != exp {
+			t.Errorf("copy mismatch: got %s", cpy.String())
+		}
+	})
+	t.Run("precedence", func(t *testing.T) {
+		s := NewSchedule().SetLocation(time.UTC)
+		_ = s.AddRange("08:00-12:00", ScheduleParams{4, 16, 0, 0})
+		_ = s.AddRange("sat,sun 00:00-*", ScheduleParams{1, 2, 0, 0})
+		_ = s.AddRange("2026-11-27 00:00-*", ScheduleParams{32, 64, 0, 0})
+		stages := []struct {
+			now string
+			max uint32
+		}{
+			{"2026-11-25T10:00:00Z", 16}, // wednesday
+			{"2026-11-25T13:00:00Z", 0},  // wednesday, no rule
+			{"2026-11-27T10:00:00Z", 64}, // black friday
+			{"2026-11-28T10:00:00Z", 2},  // saturday
+			{"2026-11-30T07:59:59Z", 0},  // monday
+			{"2026-11-30T08:00:00Z", 16}, // monday
+		}
+		for _, stage := range stages {
+			now, _ := time.Parse(time.RFC3339, stage.now)
+			params, _ := s.GetAt(now)
+			if params.WorkersMax != stage.max {
+				t.Errorf("%s: workers max mismatch: need %d, got %d", stage.now, stage.max, params.WorkersMax)
+			}
+		}
+	})
+}
+
+func TestScheduleClock(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	newSched := func() *Schedule {
+		s := NewSchedule().SetLocation(ny)
+		_ = s.AddRange("00:00-06:00", ScheduleParams{1, 1, 0, 0})
+		_ = s.AddRange("06:00-18:00", ScheduleParams{1, 2, 0, 0})
+		_ = s.AddRange("18:00-*", ScheduleParams{1, 3, 0, 0})
+		return s
+	}
+	// Walk simulated clock minute by minute over the whole local day and count minutes of each rule.
+	walk := func(s *Schedule, clk *testClock, day time.Duration) (minutes [4]int) {
+		for i := time.Duration(0); i < day; i += time.Minute {
+			params, _ := s.Get()
+			minutes[params.WorkersMax]++
+			clk.Add(time.Minute)
+		}
+		return
+	}
+	stages := []struct {
+		name  string
+		start time.Time
+		day   time.Duration
+		exp   [4]int
+	}{
+		{"regular", time.Date(2026, 1, 14, 5, 0, 0, 0, time.UTC), 24 * time.Hour, [4]int{0, 360, 720, 360}},
+		{"dst forward", time.Date(2026, 3, 8, 5, 0, 0, 0, time.UTC), 23 * time.Hour, [4]int{0, 300, 720, 360}},
+		{"dst back", time.Date(2026, 11, 1, 4, 0, 0, 0, time.UTC), 25 * time.Hour, [4]int{0, 420, 720, 360}},
+	}
+	for _, stage := range stages {
+		t.Run(stage.name, func(t *testing.T) {
+			clk := newTestClock(stage.start)
+			s := newSched().SetClock(clk)
+			if minutes := walk(s, clk, stage.day); minutes != stage.exp {
+				t.Errorf("minutes mismatch: need %v, got %v", stage.exp, minutes)
+			}
+			// Local day is over, so the next day must start from the first rule.
+			if params, _ := s.Get(); params.WorkersMax != 1 {
+				t.Errorf("next day mismatch: need 1, got %d", params.WorkersMax)
+			}
+		})
+	}
+	t.Run("queue clock", func(t *testing.T) {
+		clk := newTestClock(time.Date(2026, 3, 8, 11, 0, 0, 0, time.UTC)) // 07:00 EDT
+		q, err := New(&Config{
+			Capacity:   10,
+			WorkersMin: 1,
+			WorkersMax: 4,
+			Worker:     nopWorker{},
+			Schedule:   newSched(),
+			Clock:      clk,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = q.ForceClose() }()
+		if params, schedID := q.rtParams(); schedID != 1 || params.WorkersMax != 2 {
+			t.Errorf("params mismatch: need schedID 1 and workers max 2, got %d and %d", schedID, params.WorkersMax)
+		}
+		clk.Add(time.Hour * 11) // 18:00 EDT
+		if params, schedID := q.rtParams(); schedID != 2 || params.WorkersMax != 3 {
+			t.Errorf("params mismatch: need schedID 2 and workers max 3, got %d and %d", schedID, params.WorkersMax)
+		}
+	})
+}
+
+func TestScheduleOvernight(t *testing.T) {
+	t.Run("wrap", func(t *testing.T) {
+		s := NewSchedule().SetLocation(time.UTC)
+		if err := s.AddRange("22:00-06:00", ScheduleParams{1, 8, 0, 0}); err != nil {
+			t.Fatal(err)
+		}
+		_ = s.AddRange("12:00-13:00", ScheduleParams{1, 4, 0, 0})
+		start := time.Date(2026, 1, 14, 21, 0, 0, 0, time.UTC)
+		var ids []int
+		for i := 0; i < 12; i++ {
+			_, schedID := s.GetAt(start.Add(time.Hour * time.Duration(i)))
+			ids = append(ids, schedID)
+		}
+		exp := []int{-1, 1, 1, 1, 1, 1, 1, 1, 1, -1, -1, -1}
+		for i := range exp {
+			if ids[i] != exp[i] {
+				t.Fatalf("schedID mismatch: need %v, got %v", exp, ids)
+			}
+		}
+	})
+	t.Run("wrap weekdays", func(t *testing.T) {
+		s := NewSchedule().SetLocation(time.UTC)
+		_ = s.AddRange("fri 22:00-06:00", ScheduleParams{1, 8, 0, 0})
+		stages := []struct {
+			now string
+			id  int
+		}{
+			{"2026-01-15T23:00:00Z", -1}, // thursday night
+			{"2026-01-16T05:00:00Z", -1}, // friday morning
+			{"2026-01-16T22:00:00Z", 0},  // friday night
+			{"2026-01-17T05:59:59Z", 0},  // saturday morning
+			{"2026-01-17T06:00:00Z", -1},
+		}
+		for _, stage := range stages {
+			now, _ := time.Parse(time.RFC3339, stage.now)
+			if _, schedID := s.GetAt(now); schedID != stage.id {
+				t.Errorf("%s: schedID mismatch: need %d, got %d", stage.now, stage.id, schedID)
+			}
+		}
+	})
+	t.Run("overlap", func(t *testing.T) {
+		stages := []struct {
+			rules []string
+			err   error
+		}{
+			{[]string{"08:00-12:00", "11:00-13:00"}, ErrSchedOverlap},
+			{[]string{"08:00-12:00", "12:00-13:00"}, nil},
+			{[]string{"22:00-06:00", "05:00-07:00"}, ErrSchedOverlap},
+			{[]string{"22:00-06:00", "06:00-22:00"}, nil},
+			{[]string{"fri 22:00-06:00", "sat 05:00-07:00"}, ErrSchedOverlap},
+			{[]string{"fri 22:00-06:00", "sun 05:00-07:00"}, nil},
+			{[]string{"08:00-12:00", "mon 08:00-12:00"}, nil},
+			{[]string{"2026-01-01..2026-01-10 08:00-12:00", "2026-01-10 11:00-13:00"}, ErrSchedOverlap},
+			{[]string{"2026-01-01..2026-01-10 22:00-06:00", "2026-01-11 05:00-07:00"}, ErrSchedOverlap},
+			{[]string{"2026-01-01..2026-01-10 22:00-06:00", "2026-01-12 05:00-07:00"}, nil},
+		}
+		for _, stage := range stages {
+			s := NewSchedule()
+			var err error
+			for _, r := range stage.rules {
+				if err = s.AddRange(r, ScheduleParams{0, 1, 0, 0}); err != nil {
+					break
+				}
+			}
+			if err != stage.err {
+				t.Errorf("%v: bad error: need %v, got %v", stage.rules, stage.err, err)
+			}
+		}
+	})
+	t.Run("index", func(t *testing.T) {
+		idx := schedIndex{}
+		idx.paint(schedSeg{lt: 10, rt: 20, id: 0})
+		idx.paint(schedSeg{lt: 30, rt: 40, id: 1})
+		idx.paint(schedSeg{lt: 0, rt: 50, id: 2})
+		exp := []schedSeg{{0, 10, 2}, {10, 20, 0}, {20, 30, 2}, {30, 40, 1}, {40, 50, 2}}
+		if len(idx.segs) != len(exp) {
+			t.Fatalf("segments mismatch: need %v, got %v", exp, idx.segs)
+		}
+		for i := range exp {
+			if idx.segs[i] != exp[i] {
+				t.Fatalf("segments mismatch: need %v, got %v", exp, idx.segs)
+			}
+		}
+		for _, x := range [][2]int{{0, 2}, {15, 0}, {20, 2}, {39, 1}, {49, 2}, {50, -1}} {
+			if id := idx.get(uint32(x[0])); id != x[1] {
+				t.Errorf("lookup %d mismatch: need %d, got %d", x[0], x[1], id)
+			}
+		}
+	})
+}
+
+func BenchmarkSchedule(b *testing.B) {
+	s := NewSchedule().SetLocation(time.UTC)
+	for h := 0; h < 24; h++ {
+		_ = s.AddRange(fmt.Sprintf("%02d:00-%02d:30", h, h), ScheduleParams{1, 4, 0, 0})
+	}
+	_ = s.AddRange("sat,sun 10:00-20:00", ScheduleParams{1, 2, 0, 0})
+	now := time.Date(2026, 1, 14, 12, 15, 0, 0, time.UTC)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		s.GetAt(now)
+	}
+}
+
+func TestScheduleOverlay(t *testing.T) {
+	front := LeakDirectionFront
+	newSched := func() *Schedule {
+		s := NewSchedule().SetLocation(time.UTC)
+		_ = s.AddRangeOverlay("08:00-20:00", ScheduleParams{1, 4, 0, 0}, ScheduleOverlay{
+			RetryInterval:    time.Second,
+			DeadlineInterval: time.Minute,
+			RateLimit:        100,
+			RateBurst:        10,
+			LeakDirection:    &front,
+			QoSWeights:       map[string]uint64{"low": 300, "high": 100},
+		})
+		return s
+	}
+	t.Run("string", func(t *testing.T) {
+		exp := `[
+	08:00:00.000-20:00:00.000 min: 1 max: 4 wakeup: 0 sleep: 0 retry: 1s deadline: 1m0s rate: 100 burst: 10 leak: front weights: high=100,low=300
+]`
+		if s := newSched(); s.String() != exp {
+			t.Errorf("string mismatch: got %s", s.String())
+		}
+	})
+	t.Run("apply", func(t *testing.T) {
+		clk := newTestClock(time.Date(2026, 1, 14, 7, 59, 0, 0, time.UTC))
+		q, err := New(&Config{
+			QoS: qos.New(qos.WRR, qos.DummyPriorityEvaluator{}).
+				AddQueue(qos.Queue{Name: "high", Capacity: 10, Weight: 300}).
+				AddQueue(qos.Queue{Name: "low", Capacity: 10, Weight: 100}),
+			WorkersMin:       1,
+			WorkersMax:       4,
+			RetryInterval:    time.Millisecond,
+			DeadlineInterval: time.Second,
+			DLQ:              DummyDLQ{},
+			Worker:           nopWorker{},
+			Schedule:         newSched(),
+			Clock:            clk,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = q.ForceClose() }()
+		check := func(ri, di time.Duration, ld LeakDirection, hw uint64) {
+			if x := q.getRetryInterval(); x != ri {
+				t.Errorf("retry interval mismatch: need %s, got %s", ri, x)
+			}
+			if x := time.Duration(atomic.LoadInt64(&q.deadlineInterval)); x != di {
+				t.Errorf("deadline interval mismatch: need %s, got %s", di, x)
+			}
+			if x := q.getLeakDirection(); x != ld {
+				t.Errorf("leak direction mismatch: need %s, got %s", ld, x)
+			}
+			if x := atomic.LoadUint64(&q.c().QoS.Queues[0].EgressWeight); x != hw {
+				t.Errorf("weight mismatch: need %d, got %d", hw, x)
+			}
+		}
+		check(time.Millisecond, time.Second, LeakDirectionRear, 300)
+		clk.Add(time.Minute)
+		q.calibrate(true)
+		check(time.Second, time.Minute, LeakDirectionFront, 100)
+		clk.Add(time.Hour * 12)
+		q.calibrate(true)
+		check(time.Millisecond, time.Second, LeakDirectionRear, 300)
+	})
+}
+
+type countWorker struct {
+	n uint32
+}
+
+func (w *countWorker) Do(_ any) error {
+	atomic.AddUint32(&w.n, 1)
+	return nil
+}
+
+func TestScheduleRamp(t *testing.T) {
+	t.Run("bounds", func(t *testing.T) {
+		clk := newTestClock(time.Date(2026, 1, 14, 7, 59, 59, 0, time.UTC))
+		sched := NewSchedule().SetLocation(time.UTC)
+		_ = sched.AddRange("08:00-20:00", ScheduleParams{WorkersMin: 8, WorkersMax: 8})
+		q, err := New(&Config{
+			Capacity:          10,
+			WorkersMin:        1,
+			WorkersMax:        2,
+			HeartbeatInterval: time.Hour,
+			Schedule:          sched,
+			RampInterval:      time.Second * 10,
+			Worker:            nopWorker{},
+			Clock:             clk,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = q.ForceClose() }()
+		step := func(d time.Duration, min, max uint32) {
+			clk.Add(d)
+			q.calibrate(true)
+			if q.rtp.WorkersMin != min || q.rtp.WorkersMax != max {
+				t.Errorf("bounds mismatch: need %d/%d, got %d/%d", min, max, q.rtp.WorkersMin, q.rtp.WorkersMax)
+			}
+			if up := uint32(q.getWorkersUp()); up < min || up > max {
+				t.Errorf("workers up %d out of bounds %d/%d", up, min, max)
+			}
+		}
+		step(time.Second, 1, 2)
+		step(time.Second*5, 5, 5)
+		step(time.Second*5, 8, 8)
+		step(time.Hour*12, 8, 8)
+		step(time.Second*2, 7, 7)
+		step(time.Second*4, 4, 4)
+		step(time.Second*4, 1, 2)
+	})
+	t.Run("graceful stop", func(t *testing.T) {
+		run := func(sig signal) uint32 {
+			var w countWorker
+			q, err := New(&Config{
+				Capacity:      10,
+				Workers:       1,
+				DelayInterval: time.Millisecond * 200,
+				Worker:        &w,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = q.Enqueue(1)
+			time.Sleep(time.Millisecond * 50)
+			q.workers[0].signal(sig)
+			time.Sleep(time.Millisecond * 300)
+			_ = q.ForceClose()
+			return atomic.LoadUint32(&w.n)
+		}
+		if n := run(sigStop); n != 1 {
+			t.Errorf("graceful stop must finish current item, processed %d", n)
+		}
+		if n := run(sigForceStop); n != 0 {
+			t.Errorf("force stop must interrupt delay, processed %d", n)
+		}
+	})
+}
@@ -0,0 +1,24 @@
+package otel
+
+import (
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type Option func(tracer *tracer)
+
+// WithTracerProvider sets tracer provider to create spans.
+// If this option omit global tracer provider will use instead.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(tracer *tracer) {
+		tracer.tp = provider
+	}
+}
+
+// WithPropagator sets propagator to inject/extract trace context to/from job headers.
+// If this option omit global text map propagator will use instead.
+func WithPropagator(propagator propagation.TextMapPropagator) Option {
+	return func(tracer *tracer) {
+		tracer.prop = propagator
+	}
+}
@@ -0,0 +1,107 @@
+package otel
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type Tracer interface {
+	Inject(ctx context.Context, headers map[string]string)
+	Extract(ctx context.Context, headers map[string]string) context.Context
+	Start(ctx context.Context, name string, start time.Time, link context.Context, attrs ...any) (context.Context, func(err error))
+}
+
+const scope = "github.com/koykov/queue/trace/otel"
+
+// tracer is an OpenTelemetry implementation of queue.Tracer.
+type tracer struct {
+	tp   trace.TracerProvider
+	prop propagation.TextMapPropagator
+	t    trace.Tracer
+}
+
+// NewTracer makes a new instance of tracer.
+func NewTracer(options ...Option) Tracer {
+	t := &tracer{}
+	for _, fn := range options {
+		fn(t)
+	}
+	if t.tp == nil {
+		t.tp = otel.GetTracerProvider()
+	}
+	if t.prop == nil {
+		t.prop = otel.GetTextMapPropagator()
+	}
+	t.t = t.tp.Tracer(scope)
+	return t
+}
+
+func (t *tracer) Inject(ctx context.Context, headers map[string]string) {
+	t.prop.Inject(ctx, propagation.MapCarrier(headers))
+}
+
+func (t *tracer) Extract(ctx context.Context, headers map[string]string) context.Context {
+	if trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx
+	}
+	return t.prop.Extract(ctx, propagation.MapCarrier(headers))
+}
+
+// Start starts a new span. Span with link to producer's span has consumer kind, other spans are internal.
+func (t *tracer) Start(ctx context.Context, name string, start time.Time, link context.Context,
+	attrs ...any) (context.Context, func(err error)) {
+	opts := make([]trace.SpanStartOption, 0, 4)
+	if !start.IsZero() {
+		opts = append(opts, trace.WithTimestamp(start))
+	}
+	if link != nil {
+		if l := trace.LinkFromContext(link); l.SpanContext.IsValid() {
+			opts = append(opts, trace.WithLinks(l), trace.WithSpanKind(trace.SpanKindConsumer))
+		}
+	}
+	if len(attrs) > 1 {
+		opts = append(opts, trace.WithAttributes(kv(attrs)...))
+	}
+	ctx, span := t.t.Start(ctx, name, opts...)
+	return ctx, func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
+
+// Convert key-value pairs to attributes.
+func kv(attrs []any) []attribute.KeyValue {
+	r := make([]attribute.KeyValue, 0, len(attrs)/2)
+	for i := 0; i+1 < len(attrs); i += 2 {
+		k := fmt.Sprint(attrs[i])
+		switch v := attrs[i+1].(type) {
+		case string:
+			r = append(r, attribute.String(k, v))
+		case bool:
+			r = append(r, attribute.Bool(k, v))
+		case int:
+			r = append(r, attribute.Int(k, v))
+		case int64:
+			r = append(r, attribute.Int64(k, v))
+		case uint32:
+			r = append(r, attribute.Int64(k, int64(v)))
+		case float64:
+			r = append(r, attribute.Float64(k, v))
+		case time.Duration:
+			r = append(r, attribute.String(k, v.String()))
+		default:
+			r = append(r, attribute.String(k, fmt.Sprint(v)))
+		}
+	}
+	return r
+}
@@ -0,0 +1,56 @@
+package otel
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracer(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	tr := NewTracer(WithTracerProvider(tp), WithPropagator(propagation.TraceContext{}))
+
+	t.Run("propagation", func(t *testing.T) {
+		ctx, span := tp.Tracer("test").Start(context.Background(), "producer")
+		span.End()
+		h := map[string]string{}
+		tr.Inject(ctx, h)
+		if len(h["traceparent"]) == 0 {
+			t.Fatal("inject failed")
+		}
+		ctx1 := tr.Extract(context.Background(), h)
+		if trace.SpanContextFromContext(ctx1).TraceID() != span.SpanContext().TraceID() {
+			t.Error("extract failed")
+		}
+	})
+	t.Run("spans", func(t *testing.T) {
+		pctx, pspan := tp.Tracer("test").Start(context.Background(), "producer")
+		pspan.End()
+		start := time.Now().Add(-time.Second)
+		_, end := tr.Start(pctx, "queue.wait", start, nil, "queue.name", "test")
+		end(nil)
+		_, end = tr.Start(context.Background(), "queue.process", time.Time{}, pctx, "queue.attempt", uint32(1))
+		end(errors.New("fail"))
+
+		spans := rec.Ended()
+		wait, proc := spans[len(spans)-2], spans[len(spans)-1]
+		if wait.Parent().SpanID() != pspan.SpanContext().SpanID() || !wait.StartTime().Equal(start) {
+			t.Error("wait span mismatch")
+		}
+		if len(proc.Links()) != 1 || proc.Links()[0].SpanContext.SpanID() != pspan.SpanContext().SpanID() ||
+			proc.SpanKind() != trace.SpanKindConsumer || proc.Status().Code != codes.Error {
+			t.Error("process span mismatch")
+		}
+		if attrs := proc.Attributes(); len(attrs) != 1 || attrs[0].Value.AsInt64() != 1 {
+			t.Errorf("process span attributes mismatch: %v", attrs)
+		}
+	})
+}
@@ -0,0 +1,89 @@
+package queue
+
+import (
+	"context"
+	"time"
+)
+
+// Tracer describes distributed tracing integration.
+//
+// Queue captures producer's context on enqueue (see Queue.EnqueueCtx and Job.Headers) and emits spans for queue wait,
+// each processing attempt, retries and DLQ routing. The interface uses only builtin types, so implementations don't
+// need to import this package, see trace/otel module for OpenTelemetry implementation.
+type Tracer interface {
+	// Inject writes trace context of ctx to headers.
+	Inject(ctx context.Context, headers map[string]string)
+	// Extract reads trace context from headers. Must keep ctx as is if it already contains trace context.
+	Extract(ctx context.Context, headers map[string]string) context.Context
+	// Start starts a new span with given name as a child of ctx and returns span context and func to end the span.
+	// Zero start means current time. Span links to trace context of link if it isn't nil (producer's context).
+	// Attrs are key-value pairs.
+	Start(ctx context.Context, name string, start time.Time, link context.Context, attrs ...any) (context.Context, func(err error))
+}
+
+// Span names.
+const (
+	SpanWait    = "queue.wait"
+	SpanProcess = "queue.process"
+	SpanRetry   = "queue.retry"
+	SpanDLQ     = "queue.dlq"
+)
+
+// Span attributes.
+const (
+	traceQueue   = "queue.name"
+	traceAttempt = "queue.attempt"
+	traceDelay   = "queue.retry.delay"
+	traceReason  = "queue.dlq.reason"
+)
+
+// Capture producer's context of the item.
+func (q *Queue) traceEnqueue(ctx context.Context, itm *item) {
+	t := q.c().Tracer
+	if t == nil {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var job Job
+	switch x := itm.payload.(type) {
+	case Job:
+		job = x
+	case *Job:
+		job = *x
+	}
+	if h := job.Headers; h != nil {
+		ctx = t.Extract(ctx, h)
+		// Caller may still use the job, so inject to a copy of headers and keep it on the item.
+		job.Headers = make(map[string]string, len(h)+1)
+		for k, v := range h {
+			job.Headers[k] = v
+		}
+		t.Inject(ctx, job.Headers)
+		if _, ok := itm.payload.(Job); ok {
+			itm.payload = job
+		} else {
+			itm.payload = &job
+		}
+	}
+	itm.ctx = ctx
+}
+
+// Start span of the item.
+func (q *Queue) traceStart(ctx context.Context, name string, start time.Time, link context.Context,
+	attrs ...any) (context.Context, func(err error)) {
+	if len(q.c().Name) > 0 {
+		attrs = append(attrs, traceQueue, q.c().Name)
+	}
+	return q.c().Tracer.Start(ctx, name, start, link, attrs...)
+}
+
+// Emit DLQ routing span.
+func (q *Queue) traceDLQ(ctx context.Context, reason DLQReason, err error) {
+	if ctx == nil {
+		return
+	}
+	_, end := q.traceStart(ctx, SpanDLQ, time.Time{}, nil, traceReason, reason.String())
+	end(err)
+}
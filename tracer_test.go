@@ -0,0 +1,148 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type traceKey struct{}
+
+type testSpan struct {
+	name, parent, link string
+	err                error
+}
+
+type testTracer struct {
+	mux   sync.Mutex
+	spans []testSpan
+	done  chan struct{}
+}
+
+func (t *testTracer) Inject(ctx context.Context, headers map[string]string) {
+	if id, ok := ctx.Value(traceKey{}).(string); ok {
+		headers["trace"] = id
+	}
+}
+
+func (t *testTracer) Extract(ctx context.Context, headers map[string]string) context.Context {
+	if _, ok := ctx.Value(traceKey{}).(string); ok {
+		return ctx
+	}
+	if id, ok := headers["trace"]; ok {
+		return context.WithValue(ctx, traceKey{}, id)
+	}
+	return ctx
+}
+
+func (t *testTracer) Start(ctx context.Context, name string, _ time.Time, link context.Context,
+	_ ...any) (context.Context, func(err error)) {
+	s := testSpan{name: name}
+	s.parent, _ = ctx.Value(traceKey{}).(string)
+	if link != nil {
+		s.link, _ = link.Value(traceKey{}).(string)
+	}
+	return context.WithValue(ctx, traceKey{}, name), func(err error) {
+		s.err = err
+		t.mux.Lock()
+		t.spans = append(t.spans, s)
+		t.mux.Unlock()
+		if name == SpanDLQ {
+			close(t.done)
+		}
+	}
+}
+
+type ctxWorker struct {
+	ctx chan context.Context
+}
+
+func (w ctxWorker) Do(_ any) error { return nil }
+
+func (w ctxWorker) DoContext(ctx context.Context, _ any) error {
+	w.ctx <- ctx
+	return errors.New("fail")
+}
+
+func TestTracer(t *testing.T) {
+	t.Run("spans", func(t *testing.T) {
+		tr := &testTracer{done: make(chan struct{})}
+		w := ctxWorker{ctx: make(chan context.Context, 2)}
+		q, err := New(&Config{
+			Capacity:      10,
+			Workers:       1,
+			Worker:        w,
+			MaxRetries:    1,
+			RetryInterval: time.Millisecond,
+			Backoff:       DummyBackoff{},
+			DLQ:           DummyDLQ{},
+			FailToDLQ:     true,
+			Tracer:        tr,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		job := &Job{Payload: "foo", Headers: map[string]string{}}
+		_ = q.EnqueueCtx(context.WithValue(context.Background(), traceKey{}, "producer"), job)
+		select {
+		case <-tr.done:
+		case <-time.After(time.Second):
+			t.Fatal("DLQ span not found")
+		}
+		_ = q.Close()
+		if len(job.Headers) != 0 {
+			t.Errorf("caller's headers modified: %v", job.Headers)
+		}
+		if ctx := <-w.ctx; ctx.Value(traceKey{}) != SpanProcess {
+			t.Error("worker context mismatch")
+		}
+		tr.mux.Lock()
+		defer tr.mux.Unlock()
+		exp := []testSpan{
+			{name: SpanWait, parent: "producer"},
+			{name: SpanProcess, link: "producer"},
+			{name: SpanRetry, parent: SpanProcess},
+			{name: SpanWait, parent: "producer"},
+			{name: SpanProcess, link: "producer"},
+			{name: SpanDLQ, parent: SpanProcess},
+		}
+		if len(tr.spans) != len(exp) {
+			t.Fatalf("spans count mismatch: need %d, got %d", len(exp), len(tr.spans))
+		}
+		for i, s := range tr.spans {
+			s.err = nil
+			if s != exp[i] {
+				t.Errorf("span #%d mismatch: need %+v, got %+v", i, exp[i], s)
+			}
+		}
+	})
+	t.Run("inject", func(t *testing.T) {
+		tr := &testTracer{}
+		q, err := New(&Config{Capacity: 10, Workers: 1, Worker: nopWorker{}, Tracer: tr})
+		if err != nil {
+			t.Fatal(err)
+		}
+		job := &Job{Payload: "foo", Headers: map[string]string{}}
+		itm := item{payload: job}
+		q.traceEnqueue(context.WithValue(context.Background(), traceKey{}, "producer"), &itm)
+		if h := itm.payload.(*Job).Headers; h["trace"] != "producer" || len(job.Headers) != 0 {
+			t.Errorf("headers injection failed: %v", h)
+		}
+		_ = q.Close()
+	})
+	t.Run("extract", func(t *testing.T) {
+		tr := &testTracer{}
+		q, err := New(&Config{Capacity: 10, Workers: 1, Worker: nopWorker{}, Tracer: tr})
+		if err != nil {
+			t.Fatal(err)
+		}
+		itm := item{payload: Job{Headers: map[string]string{"trace": "remote"}}}
+		q.traceEnqueue(context.Background(), &itm)
+		if itm.ctx.Value(traceKey{}) != "remote" {
+			t.Error("headers extraction failed")
+		}
+		_ = q.Close()
+	})
+}
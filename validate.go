@@ -0,0 +1,260 @@
+package queue
+
+import "strings"
+
+// ConfigSeverity indicates how serious config problem is.
+type ConfigSeverity uint8
+
+const (
+	// ConfigWarning means that param is senseless or will be corrected (clamped or replaced by default) by queue.
+	ConfigWarning ConfigSeverity = iota
+	// ConfigFatal means that queue cannot start with such config.
+	ConfigFatal
+)
+
+func (s ConfigSeverity) String() string {
+	switch s {
+	case ConfigWarning:
+		return "warning"
+	case ConfigFatal:
+		return "error"
+	}
+	return "unknown"
+}
+
+// ConfigError describes a problem of certain config param.
+type ConfigError struct {
+	// Path of the param, eg "QoS.Queues[1].Capacity".
+	Field    string
+	Severity ConfigSeverity
+	Err      error
+}
+
+func (e *ConfigError) Error() string {
+	return e.Severity.String() + ": " + e.Field + ": " + e.Err.Error()
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// ConfigErrors is a list of all config problems.
+type ConfigErrors []*ConfigError
+
+func (e ConfigErrors) Error() string {
+	var buf strings.Builder
+	for i := 0; i < len(e); i++ {
+		if i > 0 {
+			_, _ = buf.WriteString("; ")
+		}
+		_, _ = buf.WriteString(e[i].Error())
+	}
+	return buf.String()
+}
+
+// Unwrap returns list of underlying errors.
+func (e ConfigErrors) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for i := 0; i < len(e); i++ {
+		errs = append(errs, e[i])
+	}
+	return errs
+}
+
+// Is checks if any problem matches target.
+func (e ConfigErrors) Is(target error) bool {
+	for i := 0; i < len(e); i++ {
+		if e[i].Err == target {
+			return true
+		}
+	}
+	return false
+}
+
+// Fatal returns only problems that prevent queue start.
+func (e ConfigErrors) Fatal() ConfigErrors {
+	return e.filter(ConfigFatal)
+}
+
+// Warnings returns only problems that queue corrects itself.
+func (e ConfigErrors) Warnings() ConfigErrors {
+	return e.filter(ConfigWarning)
+}
+
+// HasFatal checks if list contains problems that prevent queue start.
+func (e ConfigErrors) HasFatal() bool {
+	return len(e.Fatal()) > 0
+}
+
+func (e ConfigErrors) filter(severity ConfigSeverity) (r ConfigErrors) {
+	for i := 0; i < len(e); i++ {
+		if e[i].Severity == severity {
+			r = append(r, e[i])
+		}
+	}
+	return
+}
+
+// Validate checks config and returns all found problems at once as ConfigErrors.
+//
+// Unlike the queue init, Validate doesn't modify the config. Problems that queue corrects itself (eg, clamping of
+// factors) report as warnings, so result may be non-nil even for workable config: use HasFatal method to check if
+// queue may start. Returns nil if no problems found.
+func (c *Config) Validate() error {
+	if c == nil {
+		return ConfigErrors{{Severity: ConfigFatal, Err: ErrNoConfig}}
+	}
+	var errs ConfigErrors
+	report := func(field string, severity ConfigSeverity, err error) {
+		errs = append(errs, &ConfigError{Field: field, Severity: severity, Err: err})
+	}
+
+	// Check mandatory params.
+	if c.Capacity == 0 && c.QoS == nil {
+		report("Capacity", ConfigFatal, ErrNoCapacity)
+	}
+	if c.Worker == nil {
+		report("Worker", ConfigFatal, ErrNoWorker)
+	}
+
+	// Check workers numbers params.
+	wmin, wmax := c.WorkersMin, c.WorkersMax
+	if c.Workers > 0 && wmin == 0 {
+		wmin = c.Workers
+	}
+	if c.Workers > 0 && wmax == 0 {
+		wmax = c.Workers
+	}
+	switch {
+	case wmax == 0:
+		report("WorkersMax", ConfigFatal, ErrNoWorkers)
+	case wmax < wmin:
+		report("WorkersMin", ConfigWarning, ErrMinGtMax)
+	}
+
+	// Check factors.
+	wf, sf := c.WakeupFactor, c.SleepFactor
+	if wf < 0 {
+		report("WakeupFactor", ConfigWarning, ErrFactorNegative)
+	}
+	if wf <= 0 {
+		wf = defaultWakeupFactor
+	}
+	if wf > defaultFactorLimit {
+		report("WakeupFactor", ConfigWarning, ErrFactorLimit)
+		wf = defaultFactorLimit
+	}
+	if sf < 0 {
+		report("SleepFactor", ConfigWarning, ErrFactorNegative)
+	}
+	if sf <= 0 {
+		sf = defaultSleepFactor
+	}
+	if sf > defaultFactorLimit {
+		report("SleepFactor", ConfigWarning, ErrFactorLimit)
+		sf = defaultFactorLimit
+	}
+	if wf < sf {
+		report("WakeupFactor", ConfigWarning, ErrWakeupLtSleep)
+	}
+
+	// Check retry params.
+	if c.RetryInterval == 0 && c.Backoff != nil {
+		report("Backoff", ConfigWarning, ErrNoRetryInterval)
+	}
+	if c.RetryInterval == 0 && c.Jitter != nil {
+		report("Jitter", ConfigWarning, ErrNoRetryInterval)
+	}
+
+	// Check DLQ flags.
+	if c.DLQ == nil {
+		if c.FailToDLQ {
+			report("FailToDLQ", ConfigWarning, ErrNoDLQ)
+		}
+		if c.DeadlineToDLQ {
+			report("DeadlineToDLQ", ConfigWarning, ErrNoDLQ)
+		}
+		if c.DLQSecondary != nil {
+			report("DLQSecondary", ConfigWarning, ErrNoDLQ)
+		}
+		if c.DLQRetryTimeout > 0 {
+			report("DLQRetryTimeout", ConfigWarning, ErrNoDLQ)
+		}
+		if c.Overflow != nil {
+			report("Overflow", ConfigWarning, ErrNoDLQ)
+		}
+		if c.AQM != nil {
+			report("AQM", ConfigWarning, ErrNoDLQ)
+		}
+	}
+	if c.LeakDirection > LeakDirectionFront {
+		report("LeakDirection", ConfigFatal, ErrBadLeakDirection)
+	}
+	if c.Overflow != nil {
+		if c.OverflowThreshold < 0 {
+			report("OverflowThreshold", ConfigWarning, ErrFactorNegative)
+		}
+		if c.OverflowThreshold > defaultFactorLimit {
+			report("OverflowThreshold", ConfigWarning, ErrFactorLimit)
+		}
+	}
+
+	// Check delay and deadline.
+	if c.DelayInterval > 0 && c.DeadlineInterval > 0 && c.DeadlineInterval <= c.DelayInterval {
+		report("DeadlineInterval", ConfigFatal, ErrDeadlineLeDelay)
+	}
+
+	// Check rate limit.
+	if c.RateLimit == 0 && (c.RateInterval > 0 || c.RateBurst > 0) &&
+		(c.Schedule == nil || !c.Schedule.hasRateOverlay()) {
+		report("RateLimit", ConfigWarning, ErrNoRateLimit)
+	}
+
+	// Check breaker.
+	if b := c.Breaker; b != nil {
+		if b.FailureRate < 0 || b.FailureRate > 1 {
+			report("Breaker.FailureRate", ConfigFatal, ErrBadFailureRate)
+		}
+		if b.FailureThreshold == 0 && b.FailureRate == 0 {
+			report("Breaker", ConfigWarning, ErrBreakerNeverOpen)
+		}
+	}
+
+	// Check QoS.
+	if c.QoS != nil {
+		c.QoS.Check(func(field string, err error) {
+			report("QoS."+field, ConfigFatal, err)
+		})
+	}
+
+	// Check schedule overlays.
+	if c.Schedule != nil {
+		c.Schedule.checkOverlays(c, report)
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// Check overlays compatibility with config.
+func (s *Schedule) checkOverlays(c *Config, report func(field string, severity ConfigSeverity, err error)) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	for i := 0; i < len(s.buf); i++ {
+		o := s.buf[i].overlay
+		if o == nil {
+			continue
+		}
+		field := "Schedule[" + s.fmtTime(s.buf[i].lt) + "-" + s.fmtTime(s.buf[i].rt) + "]"
+		if o.LeakDirection != nil && *o.LeakDirection > LeakDirectionFront {
+			report(field+".LeakDirection", ConfigFatal, ErrBadLeakDirection)
+		}
+		for name := range o.QoSWeights {
+			if c.QoS == nil || !c.QoS.HasQueue(name) {
+				report(field+".QoSWeights["+name+"]", ConfigFatal, ErrUnknownSubq)
+			}
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package queue
 
 import (
+	"context"
 	"sync/atomic"
 	"time"
 )
@@ -49,27 +50,41 @@ type worker struct {
 	ctl chan struct{}
 	// Last signal timestamp.
 	lastTS int64
+	// Force flag of the last stop signal.
+	force uint32
+	// Running flag. Gracefully stopped worker may still process item when it starts again, so the flag protects
+	// worker from running twice.
+	run uint32
 	// Worker instance.
 	proc Worker
+	// Worker instance that takes context (if supported).
+	procCtx ContextWorker
 	// Config of the queue.
 	config *Config
+	// Logger of the queue.
+	log StructuredLogger
+	// Events observer of the queue.
+	obs *observer
 }
 
 // Make new idle worker.
-func makeWorker(idx uint32, config *Config) *worker {
+func makeWorker(idx uint32, config *Config, log StructuredLogger, obs *observer) *worker {
 	w := &worker{
 		idx:    idx,
 		status: WorkerStatusIdle,
 		ctl:    make(chan struct{}, 1),
 		proc:   config.Worker,
 		config: config,
+		log:    log,
+		obs:    obs,
 	}
+	w.procCtx, _ = config.Worker.(ContextWorker)
 	return w
 }
 
 // Send signal to worker.
 func (w *worker) signal(sig signal) {
-	atomic.StoreInt64(&w.lastTS, time.Now().UnixNano())
+	atomic.StoreInt64(&w.lastTS, w.c().Clock.Now().UnixNano())
 	switch sig {
 	case sigInit:
 		w.init()
@@ -84,33 +99,98 @@ func (w *worker) signal(sig signal) {
 
 // Waits to income item to process or control signal.
 func (w *worker) await(queue *Queue) {
+	for {
+		if !atomic.CompareAndSwapUint32(&w.run, 0, 1) {
+			// Previous goroutine still works and will continue.
+			return
+		}
+		w.process(queue)
+		atomic.StoreUint32(&w.run, 0)
+		if w.getStatus() != WorkerStatusActive {
+			return
+		}
+		// Worker started again while previous goroutine was exiting.
+	}
+}
+
+// Process items until worker stops.
+func (w *worker) process(queue *Queue) {
 	for {
 		switch w.getStatus() {
 		case WorkerStatusSleep:
 			// Wait config.SleepInterval.
 			<-w.ctl
 		case WorkerStatusActive:
+			// Check circuit breaker.
+			if b := queue.breaker; b != nil && !b.Allow() {
+				// Breaker is open, so pause consumption.
+				select {
+				case <-time.After(b.retryAfter()):
+				case <-w.ctl:
+				}
+				continue
+			}
+
 			// Read itm from the stream.
 			itm, ok := queue.engine.dequeue()
 			if !ok {
 				// Stream is closed. Immediately stop and exit.
+				w.cancelBreaker(queue)
 				w.stop(true)
 				return
 			}
 
+			// Check rate limit. Token takes after dequeue, so idle workers don't hold reserved tokens.
+			if tb := queue.tb; tb != nil {
+				if delay := tb.reserve(); delay > 0 && w.wait(delay) {
+					// Waiting interrupted due to force close signal, so item drops as other remaining items.
+					tb.refund()
+					queue.drop(&itm)
+					w.cancelBreaker(queue)
+					return
+				}
+			}
+
 			// Check deadline.
 			if itm.deadline > 0 {
 				now := queue.clk().Now().UnixNano()
 				if now-itm.deadline >= 0 {
-					if queue.CheckBit(flagLeaky) && w.c().DeadlineToDLQ {
-						_ = w.c().DLQ.Enqueue(itm.payload)
+					var dlq bool
+					var err error
+					if dlq = queue.CheckBit(flagLeaky) && w.c().DeadlineToDLQ; dlq {
+						err = queue.deliverDLQ(&itm, DLQReasonDeadline, LeakDirectionRear, w.wait)
+						queue.traceDLQ(itm.ctx, DLQReasonDeadline, err)
+					}
+					if w.obs != nil {
+						w.obs.notify(&DeadlineEvent{Time: queue.clk().Now(), Payload: itm.payload, DLQ: dlq, DLQErr: err})
 					}
-					w.mw().QueueDeadline()
+					if err != nil {
+						w.mw().QueueLost()
+					} else {
+						w.mw().QueueDeadline()
+					}
+					w.refundToken(queue)
+					w.cancelBreaker(queue)
 					continue
 				}
 			}
 
+			// Check early drop by AQM policy.
+			if queue.aqmDrop(&itm) {
+				_ = queue.leak(&itm, LeakDirectionFront)
+				w.refundToken(queue)
+				w.cancelBreaker(queue)
+				continue
+			}
+
 			w.mw().QueuePull()
+			if itm.enqueued > 0 {
+				w.mw().QueueWait(time.Duration(queue.clk().Now().UnixNano() - itm.enqueued))
+			}
+			if itm.ctx != nil {
+				_, end := queue.traceStart(itm.ctx, SpanWait, time.Unix(0, itm.enqueued), nil)
+				end(nil)
+			}
 
 			var intr bool
 			// Check delayed execution.
@@ -118,15 +198,10 @@ func (w *worker) await(queue *Queue) {
 				now := queue.clk().Now().UnixNano()
 				if delta := time.Duration(itm.delay - now); delta > 0 {
 					// Processing time has not yet arrived. So wait till delay ends.
-					select {
-					case <-time.After(delta):
-						break
-					case <-w.ctl:
+					if intr = w.wait(delta); intr {
 						// Waiting interrupted due to force close signal.
-						intr = true
 						// Calculate real wait time.
 						delta = time.Duration(queue.clk().Now().UnixNano() - now)
-						break
 					}
 					w.mw().WorkerWait(w.idx, delta)
 				}
@@ -134,38 +209,75 @@ func (w *worker) await(queue *Queue) {
 			if intr {
 				// Return item back to the queue due to interrupt signal.
 				_ = queue.renqueue(&itm)
+				w.cancelBreaker(queue)
 				return
 			}
 
 			// Forward itm to dequeuer.
+			ctx, end := w.traceProcess(queue, &itm)
 			now := w.config.Clock.Now()
-			err := w.proc.Do(itm.payload)
-			w.mw().QueueExec(w.config.Clock.Now().Sub(now))
+			var err error
+			if w.procCtx != nil {
+				err = w.procCtx.DoContext(ctx, itm.payload)
+			} else {
+				err = w.proc.Do(itm.payload)
+			}
+			spent := w.config.Clock.Now().Sub(now)
+			if end != nil {
+				end(err)
+			}
+			w.mw().QueueExec(spent)
+			queue.collectExec(spent, err)
+			if queue.breaker != nil {
+				queue.breaker.Report(err)
+			}
 			if err != nil {
 				// Processing failed.
 				if itm.retries < w.c().MaxRetries {
 					// Try to retry processing if possible.
-					delay := w.c().Backoff.Next(w.c().RetryInterval, int(itm.retries))
+					delay := w.c().Backoff.Next(queue.getRetryInterval(), int(itm.retries))
+					var endRetry func(error)
 					if delay > 0 {
 						// Apply jitter logic to precalculated delay.
 						delay = w.c().Jitter.Apply(delay)
-						select {
-						case <-time.After(delay):
-							// Wait for interval calculated by Backoff + Jitter.
-							break
-						case <-w.ctl:
-							intr = true
+						if itm.ctx != nil {
+							_, endRetry = queue.traceStart(ctx, SpanRetry, time.Time{}, nil, traceDelay, delay.String())
 						}
+						// Wait for interval calculated by Backoff + Jitter.
+						intr = w.wait(delay)
 					}
-					if !intr {
-						w.mw().QueueRetry(delay)
-						itm.retries++
-						itm.delay = 0 // Clear item timestamp for 2nd, 3rd, ... attempts.
-						_ = queue.renqueue(&itm)
+					if endRetry != nil {
+						endRetry(nil)
+					}
+					itm.err = err
+					if intr {
+						// Waiting interrupted due to force close signal, so item returns to the queue and drops as
+						// other remaining items.
+						w.mw().QueuePut()
+						queue.drop(&itm)
+						return
+					}
+					w.mw().QueueRetry(delay)
+					itm.retries++
+					itm.delay = 0 // Clear item timestamp for 2nd, 3rd, ... attempts.
+					itm.enqueued = queue.clk().Now().UnixNano()
+					_ = queue.renqueue(&itm)
+				} else {
+					var dlq bool
+					var dlqErr error
+					if dlq = queue.CheckBit(flagLeaky) && w.c().FailToDLQ; dlq {
+						itm.err = err
+						dlqErr = queue.deliverDLQ(&itm, DLQReasonFail, LeakDirectionRear, w.wait)
+						if itm.ctx != nil {
+							queue.traceDLQ(ctx, DLQReasonFail, dlqErr)
+						}
+					}
+					// Item already pulled from the queue, so only counters change.
+					w.mw().QueueFail(dlqErr != nil)
+					if w.obs != nil {
+						w.obs.notify(&FailEvent{Time: queue.clk().Now(), Payload: itm.payload, Retries: itm.retries,
+							Err: err, DLQ: dlq, DLQErr: dlqErr})
 					}
-				} else if queue.CheckBit(flagLeaky) && w.c().FailToDLQ {
-					_ = w.c().DLQ.Enqueue(itm.payload)
-					w.mw().QueueLeak(LeakDirectionFront.String())
 				}
 			}
 		case WorkerStatusIdle:
@@ -175,29 +287,73 @@ func (w *worker) await(queue *Queue) {
 	}
 }
 
+// Start processing span of the item. Returns background context and nil end func if tracing disabled.
+func (w *worker) traceProcess(queue *Queue, itm *item) (context.Context, func(error)) {
+	if itm.ctx == nil {
+		return context.Background(), nil
+	}
+	// Processing span starts a new trace linked to producer's span.
+	return queue.traceStart(context.Background(), SpanProcess, time.Time{}, itm.ctx, traceAttempt, itm.retries+1)
+}
+
+// Wait for given duration.
+// Only force stop signal interrupts waiting, gracefully stopped worker finishes current item.
+func (w *worker) wait(d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			return false
+		case <-w.ctl:
+			if atomic.LoadUint32(&w.force) == 1 {
+				return true
+			}
+		}
+	}
+}
+
+// Return unused rate limiter token.
+func (w *worker) refundToken(queue *Queue) {
+	if queue.tb != nil {
+		queue.tb.refund()
+	}
+}
+
+// Release breaker's call allowed for item that wasn't processed.
+func (w *worker) cancelBreaker(queue *Queue) {
+	if queue.breaker != nil {
+		queue.breaker.cancel()
+	}
+}
+
 // Start idle worker.
 func (w *worker) init() {
-	if w.l() != nil {
-		w.l().Printf("worker #%d init\n", w.idx)
+	if l := w.l(); l != nil {
+		l.Debug("worker init", logWorker, w.idx)
 	}
+	w.notify(WorkerStatusActive, false)
+	atomic.StoreUint32(&w.force, 0)
 	w.setStatus(WorkerStatusActive)
 	w.mw().WorkerInit(w.idx)
 }
 
 // Put worker to the sleep.
 func (w *worker) sleep() {
-	if w.l() != nil {
-		w.l().Printf("worker #%d sleep\n", w.idx)
+	if l := w.l(); l != nil {
+		l.Debug("worker sleep", logWorker, w.idx)
 	}
+	w.notify(WorkerStatusSleep, false)
 	w.setStatus(WorkerStatusSleep)
 	w.mw().WorkerSleep(w.idx)
 }
 
 // Wakeup sleeping worker.
 func (w *worker) wakeup() {
-	if w.l() != nil {
-		w.l().Printf("worker #%d wakeup\n", w.idx)
+	if l := w.l(); l != nil {
+		l.Debug("worker wakeup", logWorker, w.idx)
 	}
+	w.notify(WorkerStatusActive, false)
 	w.setStatus(WorkerStatusActive)
 	w.mw().WorkerWakeup(w.idx)
 	w.notifyCtl()
@@ -205,18 +361,30 @@ func (w *worker) wakeup() {
 
 // Stop (or force stop) worker.
 func (w *worker) stop(force bool) {
-	if w.l() != nil {
-		msg := "worker #%d stop\n"
+	if l := w.l(); l != nil {
+		msg := "worker stop"
 		if force {
-			msg = "worker #%d force stop\n"
+			msg = "worker force stop"
 		}
-		w.l().Printf(msg, w.idx)
+		l.Debug(msg, logWorker, w.idx, "status", w.getStatus().String())
 	}
 	w.mw().WorkerStop(w.idx, force, w.getStatus().String())
+	w.notify(WorkerStatusIdle, force)
+	if force {
+		atomic.StoreUint32(&w.force, 1)
+	}
 	w.setStatus(WorkerStatusIdle)
 	w.notifyCtl()
 }
 
+// Send worker status change event to observer.
+func (w *worker) notify(to WorkerStatus, force bool) {
+	if w.obs == nil {
+		return
+	}
+	w.obs.notify(&WorkerEvent{Time: w.c().Clock.Now(), Worker: w.idx, From: w.getStatus(), To: to, Force: force})
+}
+
 // Check if ctl channel is empty and send signal (wakeup or force close).
 func (w *worker) notifyCtl() {
 	// Check ctl channel for previously undelivered signal.
@@ -253,6 +421,6 @@ func (w *worker) mw() MetricsWriter {
 	return w.config.MetricsWriter
 }
 
-func (w *worker) l() Logger {
-	return w.config.Logger
+func (w *worker) l() StructuredLogger {
+	return w.log
 }
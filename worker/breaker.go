@@ -0,0 +1,31 @@
+package worker
+
+import "github.com/koykov/queue"
+
+// Guarded represents worker protected by circuit breaker.
+type Guarded struct {
+	breaker *queue.Breaker
+	worker  queue.Worker
+}
+
+// Guard wraps worker with circuit breaker.
+func Guard(breaker *queue.Breaker, worker queue.Worker) *Guarded {
+	w := Guarded{breaker: breaker, worker: worker}
+	return &w
+}
+
+// Do process the item if breaker allows it.
+// Returns queue.ErrBreakerOpen if breaker denies the call.
+func (w Guarded) Do(x any) (err error) {
+	if w.breaker == nil {
+		return w.worker.Do(x)
+	}
+	if !w.breaker.Allow() {
+		return queue.ErrBreakerOpen
+	}
+	err = w.worker.Do(x)
+	w.breaker.Report(err)
+	return
+}
+
+var _ = Guard